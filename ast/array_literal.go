@@ -15,6 +15,10 @@ func (al *ArrayLiteral) expressionNode() {}
 func (al *ArrayLiteral) TokenLiteral() string {
 	return al.Token.Value
 }
+
+func (al *ArrayLiteral) Pos() token.Position {
+	return al.Token.Start
+}
 func (al *ArrayLiteral) String() string {
 	var (
 		out      bytes.Buffer
@@ -0,0 +1,46 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
+
+// AssignExpression is target = value and the compound forms such as +=.
+// Target is an *Identifier, an *IndexExpression or a *MemberExpression.
+type AssignExpression struct {
+	Token    *token.Token
+	Operator string
+	Target   Expression
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Value }
+func (ae *AssignExpression) Pos() token.Position  { return ae.Token.Start }
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+// UpdateExpression is ++ or -- in prefix or postfix position.
+type UpdateExpression struct {
+	Token    *token.Token
+	Operator string
+	Prefix   bool
+	Target   Expression
+}
+
+func (ue *UpdateExpression) expressionNode()      {}
+func (ue *UpdateExpression) TokenLiteral() string { return ue.Token.Value }
+func (ue *UpdateExpression) Pos() token.Position  { return ue.Token.Start }
+func (ue *UpdateExpression) String() string {
+	if ue.Prefix {
+		return "(" + ue.Operator + ue.Target.String() + ")"
+	}
+	return "(" + ue.Target.String() + ue.Operator + ")"
+}
@@ -15,6 +15,10 @@ func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Value
 }
 
+func (bs *BlockStatement) Pos() token.Position {
+	return bs.Token.Start
+}
+
 func (bs *BlockStatement) String() string {
 	var out strings.Builder
 
@@ -15,6 +15,10 @@ func (b *Boolean) TokenLiteral() string {
 	return b.Token.Value
 }
 
+func (b *Boolean) Pos() token.Position {
+	return b.Token.Start
+}
+
 type Null struct {
 	Token *token.Token
 	Value string
@@ -27,3 +31,7 @@ func (n *Null) String() string {
 func (n *Null) TokenLiteral() string {
 	return n.Token.Value
 }
+
+func (n *Null) Pos() token.Position {
+	return n.Token.Start
+}
@@ -0,0 +1,21 @@
+package ast
+
+import "github.com/bundgaard/js/token"
+
+type BreakStatement struct {
+	Token *token.Token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Value }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Start }
+func (bs *BreakStatement) String() string       { return "break;" }
+
+type ContinueStatement struct {
+	Token *token.Token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Value }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Start }
+func (cs *ContinueStatement) String() string       { return "continue;" }
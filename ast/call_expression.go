@@ -9,10 +9,13 @@ type CallExpression struct {
 	Token     *token.Token
 	Function  Expression
 	Arguments []Expression
+	// Optional is set for fn?.(arguments).
+	Optional bool
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Value }
+func (ce *CallExpression) Pos() token.Position  { return ce.Token.Start }
 func (ce *CallExpression) String() string {
 	var out strings.Builder
 	var args []string
@@ -21,6 +24,9 @@ func (ce *CallExpression) String() string {
 	}
 
 	out.WriteString(ce.Function.String())
+	if ce.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
@@ -0,0 +1,40 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
+
+// ForInStatement covers both for (x in obj) and for (x of obj); Of tells
+// them apart. Declaration is the var, let or const keyword token, or nil when
+// the loop assigns to an existing variable.
+type ForInStatement struct {
+	Token       *token.Token
+	Declaration *token.Token
+	Name        *Identifier
+	Of          bool
+	Iterable    Expression
+	Body        *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode()       {}
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Value }
+func (fs *ForInStatement) Pos() token.Position  { return fs.Token.Start }
+func (fs *ForInStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	if fs.Declaration != nil {
+		out.WriteString(fs.Declaration.Value + " ")
+	}
+	out.WriteString(fs.Name.String())
+	if fs.Of {
+		out.WriteString(" of ")
+	} else {
+		out.WriteString(" in ")
+	}
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") {")
+	out.WriteString(fs.Body.String())
+	out.WriteString("}")
+	return out.String()
+}
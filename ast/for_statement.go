@@ -0,0 +1,39 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
+
+// ForStatement is the C-style for (init; condition; update) loop. Each of
+// the three clauses may be nil.
+type ForStatement struct {
+	Token     *token.Token
+	Init      Statement
+	Condition Expression
+	Update    Expression
+	Body      *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Value }
+func (fs *ForStatement) Pos() token.Position  { return fs.Token.Start }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	if fs.Init != nil {
+		out.WriteString(fs.Init.String())
+	}
+	out.WriteString("; ")
+	if fs.Condition != nil {
+		out.WriteString(fs.Condition.String())
+	}
+	out.WriteString("; ")
+	if fs.Update != nil {
+		out.WriteString(fs.Update.String())
+	}
+	out.WriteString(") {")
+	out.WriteString(fs.Body.String())
+	out.WriteString("}")
+	return out.String()
+}
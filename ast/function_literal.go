@@ -5,23 +5,52 @@ import (
 	"strings"
 )
 
+// FunctionLiteral is a fn or function expression, or an arrow function. An
+// arrow function with an expression body gets a body that returns the
+// expression.
 type FunctionLiteral struct {
 	Token      *token.Token
 	Name       string
 	Parameters []*Identifier
-	Body       *BlockStatement
+	// Defaults holds the default value of each parameter, or nil for a
+	// parameter without one. It is nil when no parameter has a default.
+	Defaults []Expression
+	// Rest is the ...rest parameter that collects the remaining arguments.
+	Rest  *Identifier
+	Body  *BlockStatement
+	Arrow bool
+}
+
+// ParameterStrings returns the parameters as written, with their defaults
+// and the rest parameter.
+func (fl *FunctionLiteral) ParameterStrings() []string {
+	var params []string
+	for idx, p := range fl.Parameters {
+		if idx < len(fl.Defaults) && fl.Defaults[idx] != nil {
+			params = append(params, p.String()+" = "+fl.Defaults[idx].String())
+			continue
+		}
+		params = append(params, p.String())
+	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
+	}
+	return params
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Value }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Start }
 func (fl *FunctionLiteral) String() string {
-	var (
-		out    strings.Builder
-		params []string
-	)
+	var out strings.Builder
+	params := fl.ParameterStrings()
 
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	if fl.Arrow {
+		out.WriteString("(")
+		out.WriteString(strings.Join(params, ", "))
+		out.WriteString(") => ")
+		out.WriteString(fl.Body.String())
+		return out.String()
 	}
 
 	out.WriteString(fl.TokenLiteral())
@@ -35,3 +64,15 @@ func (fl *FunctionLiteral) String() string {
 
 	return out.String()
 }
+
+// FunctionDeclaration is a named function at statement level. Unlike a
+// named function expression it binds its name in the enclosing scope.
+type FunctionDeclaration struct {
+	Token    *token.Token
+	Function *FunctionLiteral
+}
+
+func (fd *FunctionDeclaration) statementNode()       {}
+func (fd *FunctionDeclaration) TokenLiteral() string { return fd.Token.Value }
+func (fd *FunctionDeclaration) Pos() token.Position  { return fd.Token.Start }
+func (fd *FunctionDeclaration) String() string       { return fd.Function.String() }
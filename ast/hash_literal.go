@@ -9,15 +9,28 @@ import (
 type HashLiteral struct {
 	Token *token.Token
 	Pairs map[Expression]Expression
+	// Keys lists the keys of Pairs in source order. A *SpreadElement key
+	// copies the properties of its argument, which is also its value.
+	Keys []Expression
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Value }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Start }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 	var pairs []string
-	for k, v := range hl.Pairs {
-		pairs = append(pairs, k.String()+":"+v.String())
+	if hl.Keys == nil {
+		for k, v := range hl.Pairs {
+			pairs = append(pairs, k.String()+":"+v.String())
+		}
+	}
+	for _, k := range hl.Keys {
+		if spread, ok := k.(*SpreadElement); ok {
+			pairs = append(pairs, spread.String())
+			continue
+		}
+		pairs = append(pairs, k.String()+":"+hl.Pairs[k].String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
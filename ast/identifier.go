@@ -9,6 +9,7 @@ type Identifier struct {
 
 func (id *Identifier) expressionNode()      {}
 func (id *Identifier) TokenLiteral() string { return id.Token.Value }
+func (id *Identifier) Pos() token.Position  { return id.Token.Start }
 func (id *Identifier) String() string {
 	return id.Value
 }
@@ -0,0 +1,36 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
+
+type IfStatement struct {
+	Token       *token.Token
+	Condition   Expression
+	Consequence *BlockStatement
+	Alternative Statement // *BlockStatement, *IfStatement or nil
+}
+
+func (is *IfStatement) statementNode()       {}
+func (is *IfStatement) TokenLiteral() string { return is.Token.Value }
+func (is *IfStatement) Pos() token.Position  { return is.Token.Start }
+func (is *IfStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("if (")
+	out.WriteString(is.Condition.String())
+	out.WriteString(") {")
+	out.WriteString(is.Consequence.String())
+	out.WriteString("}")
+	if is.Alternative != nil {
+		out.WriteString(" else ")
+		if _, ok := is.Alternative.(*IfStatement); ok {
+			out.WriteString(is.Alternative.String())
+		} else {
+			out.WriteString("{")
+			out.WriteString(is.Alternative.String())
+			out.WriteString("}")
+		}
+	}
+	return out.String()
+}
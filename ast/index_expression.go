@@ -9,14 +9,20 @@ type IndexExpression struct {
 	Token *token.Token
 	Left  Expression
 	Index Expression
+	// Optional is set for object?.[index].
+	Optional bool
 }
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Value }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Token.Start }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -14,6 +14,7 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Value }
+func (ie *InfixExpression) Pos() token.Position  { return ie.Token.Start }
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
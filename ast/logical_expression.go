@@ -0,0 +1,51 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
+
+// LogicalExpression is a short-circuiting &&, || or ??. Right is only
+// evaluated when Left does not decide the result.
+type LogicalExpression struct {
+	Token    *token.Token
+	Left     Expression
+	Operator string
+	Right    Expression
+}
+
+func (le *LogicalExpression) expressionNode()      {}
+func (le *LogicalExpression) TokenLiteral() string { return le.Token.Value }
+func (le *LogicalExpression) Pos() token.Position  { return le.Token.Start }
+func (le *LogicalExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(le.Left.String())
+	out.WriteString(" " + le.Operator + " ")
+	out.WriteString(le.Right.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+// ConditionalExpression is the ternary test ? consequence : alternative.
+type ConditionalExpression struct {
+	Token       *token.Token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Value }
+func (ce *ConditionalExpression) Pos() token.Position  { return ce.Token.Start }
+func (ce *ConditionalExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+	return out.String()
+}
@@ -0,0 +1,40 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
+
+// MemberExpression is property access with a dot, object.property, or
+// with optional chaining, object?.property.
+type MemberExpression struct {
+	Token    *token.Token
+	Object   Expression
+	Property *Identifier
+	Optional bool
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Value }
+func (me *MemberExpression) Pos() token.Position  { return me.Token.Start }
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(me.Object.String())
+	if me.Optional {
+		out.WriteString("?")
+	}
+	out.WriteString(".")
+	out.WriteString(me.Property.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+type ThisExpression struct {
+	Token *token.Token
+}
+
+func (te *ThisExpression) expressionNode()      {}
+func (te *ThisExpression) TokenLiteral() string { return te.Token.Value }
+func (te *ThisExpression) Pos() token.Position  { return te.Token.Start }
+func (te *ThisExpression) String() string       { return "this" }
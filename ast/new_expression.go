@@ -0,0 +1,23 @@
+package ast
+
+import (
+	"github.com/bundgaard/js/token"
+	"strings"
+)
+
+type NewExpression struct {
+	Token     *token.Token
+	Callee    Expression
+	Arguments []Expression
+}
+
+func (ne *NewExpression) expressionNode()      {}
+func (ne *NewExpression) TokenLiteral() string { return ne.Token.Value }
+func (ne *NewExpression) Pos() token.Position  { return ne.Token.Start }
+func (ne *NewExpression) String() string {
+	var args []string
+	for _, a := range ne.Arguments {
+		args = append(args, a.String())
+	}
+	return "new " + ne.Callee.String() + "(" + strings.Join(args, ", ") + ")"
+}
@@ -1,17 +1,15 @@
 package ast
 
-import (
-	"fmt"
-	"github.com/bundgaard/js/token"
-)
+import "github.com/bundgaard/js/token"
 
 type NumberLiteral struct {
 	Token *token.Token
-	Value int64
+	Value float64
 }
 
 func (nl *NumberLiteral) expressionNode()      {}
 func (nl *NumberLiteral) TokenLiteral() string { return nl.Token.Value }
+func (nl *NumberLiteral) Pos() token.Position  { return nl.Token.Start }
 func (nl *NumberLiteral) String() string {
-	return fmt.Sprint(nl.Value)
+	return nl.Token.Value
 }
@@ -0,0 +1,15 @@
+package ast
+
+import "github.com/bundgaard/js/token"
+
+// OptionalChain wraps a chain of member, index and call expressions with at
+// least one ?. link. When an optional link finds null or undefined the rest
+// of the chain is skipped and the whole chain is undefined.
+type OptionalChain struct {
+	Expression Expression
+}
+
+func (oc *OptionalChain) expressionNode()      {}
+func (oc *OptionalChain) TokenLiteral() string { return oc.Expression.TokenLiteral() }
+func (oc *OptionalChain) Pos() token.Position  { return oc.Expression.Pos() }
+func (oc *OptionalChain) String() string       { return oc.Expression.String() }
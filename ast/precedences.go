@@ -5,20 +5,52 @@ import "github.com/bundgaard/js/token"
 const (
 	_ int = iota
 	Lowest
+	Assign
+	Conditional
+	LogicalOr
+	LogicalAnd
 	Equals
 	LessGreater
 	Sum
 	Product
+	Exponent
 	Prefix
+	Postfix
 	Call
 	Index
 )
 
 var Precedences = map[token.Type]int{
-	token.Add:         Sum,
-	token.Sub:         Sum,
-	token.Mul:         Product,
-	token.Div:         Product,
-	token.OpenBracket: Index,
-	token.OpenParen:   Call,
+	token.Equal:          Equals,
+	token.NotEqual:       Equals,
+	token.StrictEqual:    Equals,
+	token.StrictNotEqual: Equals,
+	token.Less:           LessGreater,
+	token.Greater:        LessGreater,
+	token.LessEqual:      LessGreater,
+	token.GreaterEqual:   LessGreater,
+	token.Add:            Sum,
+	token.Sub:            Sum,
+	token.Mul:            Product,
+	token.Div:            Product,
+	token.Mod:            Product,
+	token.Pow:            Exponent,
+	token.Assign:         Assign,
+	token.AddAssign:      Assign,
+	token.SubAssign:      Assign,
+	token.MulAssign:      Assign,
+	token.DivAssign:      Assign,
+	token.ModAssign:      Assign,
+	token.PowAssign:      Assign,
+	token.Arrow:          Assign,
+	token.Increment:      Postfix,
+	token.Decrement:      Postfix,
+	token.OpenBracket:    Index,
+	token.Dot:            Index,
+	token.OpenParen:      Call,
+	token.OptionalChain:  Index,
+	token.Question:       Conditional,
+	token.Or:             LogicalOr,
+	token.Nullish:        LogicalOr,
+	token.And:            LogicalAnd,
 }
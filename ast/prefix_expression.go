@@ -0,0 +1,29 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
+
+// PrefixExpression is a unary operator applied to its operand: !x, -x, +x
+// and typeof x.
+type PrefixExpression struct {
+	Token    *token.Token
+	Operator string
+	Right    Expression
+}
+
+func (pe *PrefixExpression) expressionNode()      {}
+func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Value }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Start }
+func (pe *PrefixExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(pe.Operator)
+	if pe.Token.Type == token.Typeof {
+		out.WriteString(" ")
+	}
+	out.WriteString(pe.Right.String())
+	out.WriteString(")")
+	return out.String()
+}
@@ -1,6 +1,9 @@
 package ast
 
-import "bytes"
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
 
 type Program struct {
 	Statements []Statement
@@ -14,6 +17,14 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+// Pos returns the position of the first statement.
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
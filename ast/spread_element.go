@@ -0,0 +1,15 @@
+package ast
+
+import "github.com/bundgaard/js/token"
+
+// SpreadElement is ...argument in call arguments and array and object
+// literals. It stands for all the values of argument.
+type SpreadElement struct {
+	Token    *token.Token
+	Argument Expression
+}
+
+func (se *SpreadElement) expressionNode()      {}
+func (se *SpreadElement) TokenLiteral() string { return se.Token.Value }
+func (se *SpreadElement) Pos() token.Position  { return se.Token.Start }
+func (se *SpreadElement) String() string       { return "..." + se.Argument.String() }
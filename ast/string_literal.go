@@ -9,6 +9,7 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Value }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Start }
 func (sl *StringLiteral) String() string {
 	return sl.Value
 }
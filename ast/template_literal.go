@@ -0,0 +1,32 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
+
+// TemplateLiteral is a backtick string. Strings holds the text around the
+// ${} substitutions, so it always has one more element than Expressions.
+type TemplateLiteral struct {
+	Token       *token.Token
+	Strings     []string
+	Expressions []Expression
+}
+
+func (tl *TemplateLiteral) expressionNode()      {}
+func (tl *TemplateLiteral) TokenLiteral() string { return tl.Token.Value }
+func (tl *TemplateLiteral) Pos() token.Position  { return tl.Token.Start }
+func (tl *TemplateLiteral) String() string {
+	var out bytes.Buffer
+	out.WriteString("`")
+	for idx, s := range tl.Strings {
+		out.WriteString(s)
+		if idx < len(tl.Expressions) {
+			out.WriteString("${")
+			out.WriteString(tl.Expressions[idx].String())
+			out.WriteString("}")
+		}
+	}
+	out.WriteString("`")
+	return out.String()
+}
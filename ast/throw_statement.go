@@ -0,0 +1,15 @@
+package ast
+
+import "github.com/bundgaard/js/token"
+
+type ThrowStatement struct {
+	Token    *token.Token
+	Argument Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Value }
+func (ts *ThrowStatement) Pos() token.Position  { return ts.Token.Start }
+func (ts *ThrowStatement) String() string {
+	return "throw " + ts.Argument.String() + ";"
+}
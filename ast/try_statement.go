@@ -0,0 +1,41 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
+
+// TryStatement is try { } catch (param) { } finally { }. Either Handler or
+// Finalizer may be nil, not both. Param is nil for catch { }.
+type TryStatement struct {
+	Token     *token.Token
+	Block     *BlockStatement
+	Param     *Identifier
+	Handler   *BlockStatement
+	Finalizer *BlockStatement
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Value }
+func (ts *TryStatement) Pos() token.Position  { return ts.Token.Start }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("try {")
+	out.WriteString(ts.Block.String())
+	out.WriteString("}")
+	if ts.Handler != nil {
+		out.WriteString(" catch ")
+		if ts.Param != nil {
+			out.WriteString("(" + ts.Param.String() + ") ")
+		}
+		out.WriteString("{")
+		out.WriteString(ts.Handler.String())
+		out.WriteString("}")
+	}
+	if ts.Finalizer != nil {
+		out.WriteString(" finally {")
+		out.WriteString(ts.Finalizer.String())
+		out.WriteString("}")
+	}
+	return out.String()
+}
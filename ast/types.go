@@ -1,8 +1,13 @@
 package ast
 
+import "github.com/bundgaard/js/token"
+
 type Node interface {
 	TokenLiteral() string
 	String() string
+	// Pos returns the start of the token the node was parsed from, such as
+	// the operator of an infix expression or the keyword of a statement.
+	Pos() token.Position
 }
 
 type Statement interface {
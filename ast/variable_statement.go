@@ -13,12 +13,13 @@ type VariableStatement struct {
 
 func (vs *VariableStatement) statementNode()       {}
 func (vs *VariableStatement) TokenLiteral() string { return vs.Token.Value }
+func (vs *VariableStatement) Pos() token.Position  { return vs.Token.Start }
 func (vs *VariableStatement) String() string {
 	out := new(bytes.Buffer)
 	out.WriteString(vs.TokenLiteral() + " ")
 	out.WriteString(vs.Name.String())
-	out.WriteString(" = ")
 	if vs.Value != nil {
+		out.WriteString(" = ")
 		out.WriteString(vs.Value.String())
 	}
 	return out.String()
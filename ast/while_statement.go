@@ -0,0 +1,25 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/bundgaard/js/token"
+)
+
+type WhileStatement struct {
+	Token     *token.Token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Value }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Start }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("while (")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(") {")
+	out.WriteString(ws.Body.String())
+	out.WriteString("}")
+	return out.String()
+}
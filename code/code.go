@@ -0,0 +1,287 @@
+// Package code defines the bytecode the compiler produces and the virtual
+// machine runs.
+package code
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+type Instructions []byte
+
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpNil
+	OpNull
+	OpUndefined
+	OpTrue
+	OpFalse
+
+	OpPop
+	OpComplete
+	OpCompletion
+	OpDup
+	OpDup2
+
+	OpBinary
+	OpPrefix
+
+	OpGetName
+	OpTypeofName
+	OpAssignName
+	OpDefine
+	OpDeclare
+	OpThis
+	OpArgument
+
+	OpPushScope
+	OpPopScope
+	OpCopyScope
+
+	OpJump
+	OpJumpIfFalse
+	OpJumpIfFalseKeep
+	OpJumpIfTrueKeep
+	OpJumpIfNotNullishKeep
+	OpJumpIfNotUndefined
+	OpJumpIfNullish
+
+	OpArray
+	OpHash
+	OpTemplate
+	OpSpread
+
+	OpIndex
+	OpMember
+	OpIndexRef
+	OpMemberRef
+	OpSetIndex
+	OpSetMember
+	OpUpdateName
+	OpUpdateIndex
+	OpUpdateMember
+
+	OpCall
+	OpCallMethod
+	OpNew
+	OpClosure
+	OpReturn
+	OpThrow
+
+	OpTry
+	OpEndTry
+	OpCatch
+	OpIter
+	OpIterNext
+)
+
+// Definition describes an opcode: its name and the width in bytes of each
+// of its operands.
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:  {"OpConstant", []int{2}},
+	OpNil:       {"OpNil", []int{}},
+	OpNull:      {"OpNull", []int{}},
+	OpUndefined: {"OpUndefined", []int{}},
+	OpTrue:      {"OpTrue", []int{}},
+	OpFalse:     {"OpFalse", []int{}},
+
+	// OpComplete pops the value of an expression statement and keeps it as
+	// the completion value, which OpCompletion pushes back.
+	OpPop:        {"OpPop", []int{}},
+	OpComplete:   {"OpComplete", []int{}},
+	OpCompletion: {"OpCompletion", []int{}},
+	OpDup:        {"OpDup", []int{}},
+	OpDup2:       {"OpDup2", []int{}},
+
+	// The operand of OpBinary and OpPrefix indexes Operators.
+	OpBinary: {"OpBinary", []int{1}},
+	OpPrefix: {"OpPrefix", []int{1}},
+
+	// Names are string constants. OpDeclare also takes the token.Type of
+	// the declaration keyword. OpArgument pushes the argument of the call at
+	// its operand, or undefined when there are fewer arguments.
+	OpGetName:    {"OpGetName", []int{2}},
+	OpTypeofName: {"OpTypeofName", []int{2}},
+	OpAssignName: {"OpAssignName", []int{2}},
+	OpDefine:     {"OpDefine", []int{2}},
+	OpDeclare:    {"OpDeclare", []int{2, 1}},
+	OpThis:       {"OpThis", []int{}},
+	OpArgument:   {"OpArgument", []int{2}},
+
+	OpPushScope: {"OpPushScope", []int{}},
+	OpPopScope:  {"OpPopScope", []int{}},
+	OpCopyScope: {"OpCopyScope", []int{}},
+
+	// The Keep jumps leave the value on the stack when they jump and pop it
+	// when they do not.
+	OpJump:                 {"OpJump", []int{2}},
+	OpJumpIfFalse:          {"OpJumpIfFalse", []int{2}},
+	OpJumpIfFalseKeep:      {"OpJumpIfFalseKeep", []int{2}},
+	OpJumpIfTrueKeep:       {"OpJumpIfTrueKeep", []int{2}},
+	OpJumpIfNotNullishKeep: {"OpJumpIfNotNullishKeep", []int{2}},
+	OpJumpIfNotUndefined:   {"OpJumpIfNotUndefined", []int{2}},
+	// OpJumpIfNullish ends an optional chain: if the value is null or
+	// undefined, it drops it and as many values below it as its second
+	// operand says, and jumps with undefined on the stack.
+	OpJumpIfNullish: {"OpJumpIfNullish", []int{2, 1}},
+
+	OpArray:    {"OpArray", []int{2}},
+	OpHash:     {"OpHash", []int{2}},
+	OpTemplate: {"OpTemplate", []int{2}},
+	// OpSpread marks a value whose elements OpArray, OpCall, OpCallMethod
+	// and OpNew expand in place. In OpHash a null key marks a spread.
+	OpSpread: {"OpSpread", []int{}},
+
+	// The update operand has UpdateDecrement and UpdatePrefix set as
+	// needed.
+	OpIndex:  {"OpIndex", []int{}},
+	OpMember: {"OpMember", []int{2}},
+	// OpIndexRef and OpMemberRef read the target of a compound assignment,
+	// failing first if it cannot be assigned.
+	OpIndexRef:     {"OpIndexRef", []int{}},
+	OpMemberRef:    {"OpMemberRef", []int{2}},
+	OpSetIndex:     {"OpSetIndex", []int{}},
+	OpSetMember:    {"OpSetMember", []int{2}},
+	OpUpdateName:   {"OpUpdateName", []int{2, 1}},
+	OpUpdateIndex:  {"OpUpdateIndex", []int{1}},
+	OpUpdateMember: {"OpUpdateMember", []int{2, 1}},
+
+	// OpNew takes the callee as written, for error messages, and the
+	// argument count. OpClosure takes the constant index of the function and
+	// whether it is a named function expression, whose name is bound in its
+	// own scope.
+	OpCall:       {"OpCall", []int{1}},
+	OpCallMethod: {"OpCallMethod", []int{1}},
+	OpNew:        {"OpNew", []int{2, 1}},
+	OpClosure:    {"OpClosure", []int{2, 1}},
+	OpReturn:     {"OpReturn", []int{}},
+	OpThrow:      {"OpThrow", []int{}},
+
+	// OpTry installs a handler that jumps to its operand with the error on
+	// the stack; OpEndTry removes it. OpCatch turns the error into the
+	// value a catch clause binds. OpThrow rethrows an error as it is.
+	OpTry:    {"OpTry", []int{2}},
+	OpEndTry: {"OpEndTry", []int{}},
+	OpCatch:  {"OpCatch", []int{}},
+
+	// OpIter replaces a value with an iterator over its values, if the
+	// operand is 1, or its keys. OpIterNext pushes the next one or jumps
+	// when there are none left, leaving the iterator on the stack.
+	OpIter:     {"OpIter", []int{1}},
+	OpIterNext: {"OpIterNext", []int{2}},
+}
+
+// Flags of the update operand.
+const (
+	UpdateDecrement = 1 << iota
+	UpdatePrefix
+)
+
+// Operators lists the binary and prefix operators by operand value.
+var Operators = []string{
+	"+", "-", "*", "/", "%", "**",
+	"==", "!=", "===", "!==", "<", ">", "<=", ">=",
+	"!", "typeof",
+}
+
+// Operator returns the operand value of operator.
+func Operator(operator string) (int, bool) {
+	for i, op := range Operators {
+		if op == operator {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+// Make encodes an instruction. It panics if an operand does not fit in its
+// width rather than encode a different one.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+	offset := 1
+	for i, o := range operands {
+		if o < 0 || o >= 1<<(8*uint(def.OperandWidths[i])) {
+			panic(fmt.Sprintf("%s operand %d out of range", def.Name, o))
+		}
+		switch def.OperandWidths[i] {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += def.OperandWidths[i]
+	}
+	return instruction
+}
+
+// ReadOperands decodes the operands of an instruction and returns them with
+// the number of bytes read.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
+
+func (ins Instructions) String() string {
+	var out strings.Builder
+	for i := 0; i < len(ins); {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s", i, def.Name)
+		for _, operand := range operands {
+			fmt.Fprintf(&out, " %d", operand)
+		}
+		out.WriteString("\n")
+		i += 1 + read
+	}
+	return out.String()
+}
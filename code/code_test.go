@@ -0,0 +1,64 @@
+package code
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpPop, []int{}, []byte{byte(OpPop)}},
+		{OpDeclare, []int{258, 7}, []byte{byte(OpDeclare), 1, 2, 7}},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+		if string(instruction) != string(tt.expected) {
+			t.Errorf("expected %v. got %v", tt.expected, instruction)
+		}
+	}
+}
+
+func TestMakeOutOfRange(t *testing.T) {
+	for _, operands := range [][]int{{70000}, {-1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected operands %v to panic", operands)
+				}
+			}()
+			Make(OpConstant, operands...)
+		}()
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	var ins Instructions
+	ins = append(ins, Make(OpGetName, 1)...)
+	ins = append(ins, Make(OpConstant, 2)...)
+	ins = append(ins, Make(OpBinary, 0)...)
+	ins = append(ins, Make(OpReturn)...)
+
+	expected := `0000 OpGetName 1
+0003 OpConstant 2
+0006 OpBinary 0
+0008 OpReturn
+`
+	if ins.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, ins.String())
+	}
+}
+
+func TestPositionsAt(t *testing.T) {
+	positions := Positions{{Offset: 0}, {Offset: 4}, {Offset: 9}}
+	positions[1].Position.Line = 2
+	positions[2].Position.Line = 3
+
+	for offset, line := range map[int]int{0: 0, 3: 0, 4: 2, 8: 2, 9: 3, 20: 3} {
+		if got := positions.At(offset).Line; got != line {
+			t.Errorf("offset %d: expected line %d. got %d", offset, line, got)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package code
+
+import (
+	"github.com/bundgaard/js/token"
+	"sort"
+)
+
+// Mark records that the instructions from Offset on were compiled from the
+// source at Position.
+type Mark struct {
+	Offset   int
+	Position token.Position
+}
+
+// Positions maps instruction offsets back to the source. Marks are sorted
+// by offset.
+type Positions []Mark
+
+// At returns the source position of the instruction at offset.
+func (p Positions) At(offset int) token.Position {
+	i := sort.Search(len(p), func(i int) bool { return p[i].Offset > offset })
+	if i == 0 {
+		return token.Position{}
+	}
+	return p[i-1].Position
+}
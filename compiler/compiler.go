@@ -0,0 +1,230 @@
+// Package compiler compiles a parsed program to bytecode for package vm.
+//
+// The bytecode does what eval.Eval does for the same program: variables are
+// still looked up by name in an object.Environment and all operators are
+// the ones package eval uses, so the two engines give the same results and
+// the same errors.
+package compiler
+
+import (
+	"fmt"
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/code"
+	"github.com/bundgaard/js/object"
+	"github.com/bundgaard/js/token"
+	"math"
+)
+
+// Error is a program the compiler rejects, such as a return statement
+// outside of a function.
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+func (e *Error) Error() string {
+	if e.Position.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Position, e.Message)
+	}
+	return e.Message
+}
+
+// Compile compiles program to a function without parameters that runs it
+// and returns the value of its last statement.
+func Compile(program *ast.Program) (fn *object.CompiledFunction, err error) {
+	c := &Compiler{}
+	c.enter(&object.CompiledFunction{Source: "<program>"})
+	defer func() {
+		if r := recover(); r != nil {
+			cerr, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			fn, err = nil, cerr
+		}
+	}()
+
+	c.pos = program.Pos()
+	for _, statement := range program.Statements {
+		c.statement(statement)
+	}
+	c.emit(code.OpCompletion)
+	c.emit(code.OpReturn)
+	return c.leave(), nil
+}
+
+// Compiler holds the state of a compilation. Functions are compiled in
+// scopes of their own, so each gets its own instructions and constants.
+type Compiler struct {
+	scope *scope
+	// pos is the position of the node being compiled. Instructions are
+	// marked with it, so a runtime error is reported at the innermost node
+	// it came from, as eval.Eval does.
+	pos token.Position
+}
+
+type scope struct {
+	fn    *object.CompiledFunction
+	names map[string]int
+	// numbers holds the constant index of each number by its bits, so -0
+	// and 0 stay apart.
+	numbers map[uint64]int
+	control []*control
+	// chain collects the jumps to the end of the optional chain being
+	// compiled.
+	chain *[]int
+	// discard is set while compiling a finally block, whose statements do
+	// not change the completion value.
+	discard bool
+	outer   *scope
+}
+
+func (c *Compiler) enter(fn *object.CompiledFunction) {
+	c.scope = &scope{fn: fn, names: make(map[string]int), numbers: make(map[uint64]int), outer: c.scope}
+}
+
+func (c *Compiler) leave() *object.CompiledFunction {
+	fn := c.scope.fn
+	c.scope = c.scope.outer
+	return fn
+}
+
+func (c *Compiler) errorf(format string, v ...interface{}) {
+	panic(&Error{Position: c.pos, Message: fmt.Sprintf(format, v...)})
+}
+
+// at sets the position of the node being compiled and returns a function
+// that restores the previous one, for use with defer.
+func (c *Compiler) at(n ast.Node) func() {
+	saved := c.pos
+	c.pos = n.Pos()
+	return func() { c.pos = saved }
+}
+
+// emit appends an instruction and returns its offset. An operand too large
+// for its width is an error rather than being cut short.
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	def, err := code.Lookup(byte(op))
+	if err != nil {
+		c.errorf("%s", err)
+	}
+	for i, operand := range operands {
+		if operand < 0 || operand >= 1<<(8*uint(def.OperandWidths[i])) {
+			c.errorf("%s operand %d out of range", def.Name, operand)
+		}
+	}
+	fn := c.scope.fn
+	offset := len(fn.Instructions)
+	if n := len(fn.Positions); n == 0 || fn.Positions[n-1].Position != c.pos {
+		fn.Positions = append(fn.Positions, code.Mark{Offset: offset, Position: c.pos})
+	}
+	fn.Instructions = append(fn.Instructions, code.Make(op, operands...)...)
+	return offset
+}
+
+// emitCall emits a call instruction and records call as the position the
+// call is made from in stack traces.
+func (c *Compiler) emitCall(call token.Position, op code.Opcode, operands ...int) {
+	offset := c.emit(op, operands...)
+	c.scope.fn.Calls = append(c.scope.fn.Calls, code.Mark{Offset: offset, Position: call})
+}
+
+// complete pops the value of a statement into the completion value.
+func (c *Compiler) complete() {
+	if c.scope.discard {
+		c.emit(code.OpPop)
+		return
+	}
+	c.emit(code.OpComplete)
+}
+
+func (c *Compiler) constant(obj object.Object) int {
+	fn := c.scope.fn
+	fn.Constants = append(fn.Constants, obj)
+	if len(fn.Constants) > 1<<16 {
+		c.errorf("too many constants")
+	}
+	return len(fn.Constants) - 1
+}
+
+// number returns the constant index of n, adding it once per function.
+func (c *Compiler) number(n float64) int {
+	bits := math.Float64bits(n)
+	if idx, ok := c.scope.numbers[bits]; ok {
+		return idx
+	}
+	idx := c.constant(&object.NumberObject{Value: n})
+	c.scope.numbers[bits] = idx
+	return idx
+}
+
+// name returns the constant index of a string, such as a variable name,
+// adding it once per function.
+func (c *Compiler) name(s string) int {
+	if idx, ok := c.scope.names[s]; ok {
+		return idx
+	}
+	idx := c.constant(&object.StringObject{Value: s})
+	c.scope.names[s] = idx
+	return idx
+}
+
+// variable returns the constant index of the name of a variable.
+func (c *Compiler) variable(name string) int {
+	if name == "arguments" {
+		c.uses(func(fn *object.CompiledFunction) { fn.UsesArguments = true })
+	}
+	return c.name(name)
+}
+
+// uses marks the function that binds arguments and this for the code being
+// compiled: the innermost function that is not an arrow function.
+func (c *Compiler) uses(mark func(fn *object.CompiledFunction)) {
+	for s := c.scope; s.outer != nil; s = s.outer {
+		if !s.fn.Arrow {
+			mark(s.fn)
+			return
+		}
+	}
+}
+
+func (c *Compiler) operator(operator string) int {
+	op, ok := code.Operator(operator)
+	if !ok {
+		c.errorf("unknown operator %s", operator)
+	}
+	return op
+}
+
+// jump emits a jump to be patched later and returns its offset.
+func (c *Compiler) jump(op code.Opcode, operands ...int) int {
+	return c.emit(op, append([]int{0xffff}, operands...)...)
+}
+
+// jumpBack emits a jump to target, the offset of an earlier instruction.
+func (c *Compiler) jumpBack(target int) {
+	if target > 0xffff {
+		c.errorf("function too large")
+	}
+	c.emit(code.OpJump, target)
+}
+
+// patch makes the jump at offset go to the next instruction.
+func (c *Compiler) patch(offset int) {
+	c.patchTo(offset, c.here())
+}
+
+// patchTo makes the jump at offset go to target.
+func (c *Compiler) patchTo(offset, target int) {
+	if target > 0xffff {
+		c.errorf("function too large")
+	}
+	ins := c.scope.fn.Instructions
+	ins[offset+1] = byte(target >> 8)
+	ins[offset+2] = byte(target)
+}
+
+// here returns the offset of the next instruction, for backward jumps.
+func (c *Compiler) here() int {
+	return len(c.scope.fn.Instructions)
+}
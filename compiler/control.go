@@ -0,0 +1,120 @@
+package compiler
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/code"
+)
+
+type controlKind int
+
+const (
+	// controlScope is a scope pushed with OpPushScope.
+	controlScope controlKind = iota
+	// controlValue is a value kept on the stack, such as the iterator of a
+	// for...in loop.
+	controlValue
+	// controlHandler is a catch handler installed with OpTry.
+	controlHandler
+	// controlFinally is the handler of a finally block, which also has to
+	// run when a jump leaves the try statement.
+	controlFinally
+	controlLoop
+)
+
+// control is an entry of the stack of constructs the code being compiled
+// is nested in. Jumps out of them, break, continue and return, have to undo
+// what they set up.
+type control struct {
+	kind      controlKind
+	finalizer *ast.BlockStatement
+	// breaks and continues are the jumps out of a loop to be patched.
+	breaks    []int
+	continues []int
+}
+
+func (c *Compiler) push(entry *control) {
+	c.scope.control = append(c.scope.control, entry)
+}
+
+func (c *Compiler) pop() *control {
+	stack := c.scope.control
+	top := stack[len(stack)-1]
+	c.scope.control = stack[:len(stack)-1]
+	return top
+}
+
+// unwind emits the code that leaves the entries of the control stack above
+// depth. A return keeps the value it returns on the stack.
+func (c *Compiler) unwind(depth int, returning bool) {
+	for i := len(c.scope.control) - 1; i >= depth; i-- {
+		switch entry := c.scope.control[i]; entry.kind {
+		case controlScope:
+			c.emit(code.OpPopScope)
+		case controlValue:
+			if !returning {
+				c.emit(code.OpPop)
+			}
+		case controlHandler:
+			c.emit(code.OpEndTry)
+		case controlFinally:
+			c.emit(code.OpEndTry)
+			// The finally block runs outside of its own try statement.
+			saved := c.scope.control
+			c.scope.control = saved[:i:i]
+			c.finally(entry.finalizer)
+			c.scope.control = saved
+		}
+	}
+}
+
+// loop returns the index of the innermost loop on the control stack, or -1.
+func (c *Compiler) loop() int {
+	for i := len(c.scope.control) - 1; i >= 0; i-- {
+		if c.scope.control[i].kind == controlLoop {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) breakStatement(node *ast.BreakStatement) {
+	i := c.loop()
+	if i < 0 {
+		c.errorf("illegal break statement")
+	}
+	c.unwind(i+1, false)
+	jump := c.jump(code.OpJump)
+	c.scope.control[i].breaks = append(c.scope.control[i].breaks, jump)
+}
+
+func (c *Compiler) continueStatement(node *ast.ContinueStatement) {
+	i := c.loop()
+	if i < 0 {
+		c.errorf("illegal continue statement")
+	}
+	c.unwind(i+1, false)
+	jump := c.jump(code.OpJump)
+	c.scope.control[i].continues = append(c.scope.control[i].continues, jump)
+}
+
+func (c *Compiler) returnStatement(node *ast.ReturnStatement) {
+	if c.scope.outer == nil {
+		c.errorf("illegal return statement outside of function")
+	}
+	if node.ReturnValue == nil {
+		c.emit(code.OpUndefined)
+	} else {
+		c.expression(node.ReturnValue)
+	}
+	c.unwind(0, true)
+	c.emit(code.OpReturn)
+}
+
+// finally compiles a finally block, which leaves the completion value
+// alone.
+func (c *Compiler) finally(block *ast.BlockStatement) {
+	saved := c.scope.discard
+	c.scope.discard = true
+	c.block(block)
+	c.scope.discard = saved
+}
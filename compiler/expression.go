@@ -0,0 +1,336 @@
+package compiler
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/code"
+	"github.com/bundgaard/js/object"
+)
+
+// expression compiles an expression, which pushes its value.
+func (c *Compiler) expression(n ast.Expression) {
+	defer c.at(n)()
+
+	switch n := n.(type) {
+	case *ast.NumberLiteral:
+		c.emit(code.OpConstant, c.number(n.Value))
+	case *ast.StringLiteral:
+		c.emit(code.OpConstant, c.name(n.Value))
+	case *ast.Boolean:
+		if n.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+	case *ast.Null:
+		c.emit(code.OpNull)
+	case *ast.ThisExpression:
+		c.uses(func(fn *object.CompiledFunction) { fn.UsesThis = true })
+		c.emit(code.OpThis)
+	case *ast.Identifier:
+		c.emit(code.OpGetName, c.variable(n.Value))
+	case *ast.TemplateLiteral:
+		for idx, s := range n.Strings {
+			c.emit(code.OpConstant, c.name(s))
+			if idx < len(n.Expressions) {
+				c.expression(n.Expressions[idx])
+			}
+		}
+		c.emit(code.OpTemplate, c.count(len(n.Strings)+len(n.Expressions), 0xffff, "template parts"))
+	case *ast.ArrayLiteral:
+		c.elements(n.Elements)
+		c.emit(code.OpArray, c.count(len(n.Elements), 0xffff, "array elements"))
+	case *ast.HashLiteral:
+		c.hashLiteral(n)
+	case *ast.FunctionLiteral:
+		c.function(n, n.Name != "")
+
+	case *ast.PrefixExpression:
+		if ident, ok := n.Right.(*ast.Identifier); ok && n.Operator == "typeof" {
+			// typeof is the one operator that accepts an undeclared name.
+			c.emit(code.OpTypeofName, c.variable(ident.Value))
+			return
+		}
+		c.expression(n.Right)
+		c.emit(code.OpPrefix, c.operator(n.Operator))
+	case *ast.InfixExpression:
+		c.expression(n.Left)
+		c.expression(n.Right)
+		c.emit(code.OpBinary, c.operator(n.Operator))
+	case *ast.LogicalExpression:
+		c.expression(n.Left)
+		var end int
+		switch n.Operator {
+		case "&&":
+			end = c.jump(code.OpJumpIfFalseKeep)
+		case "||":
+			end = c.jump(code.OpJumpIfTrueKeep)
+		case "??":
+			end = c.jump(code.OpJumpIfNotNullishKeep)
+		default:
+			c.errorf("unknown operator %s", n.Operator)
+		}
+		c.expression(n.Right)
+		c.patch(end)
+	case *ast.ConditionalExpression:
+		c.expression(n.Condition)
+		otherwise := c.jump(code.OpJumpIfFalse)
+		c.expression(n.Consequence)
+		end := c.jump(code.OpJump)
+		c.patch(otherwise)
+		c.expression(n.Alternative)
+		c.patch(end)
+
+	case *ast.AssignExpression:
+		c.assign(n)
+	case *ast.UpdateExpression:
+		c.update(n)
+
+	case *ast.MemberExpression:
+		c.expression(n.Object)
+		c.optional(n.Optional, 0)
+		c.emit(code.OpMember, c.name(n.Property.Value))
+	case *ast.IndexExpression:
+		c.expression(n.Left)
+		c.optional(n.Optional, 0)
+		c.expression(n.Index)
+		c.emit(code.OpIndex)
+	case *ast.OptionalChain:
+		saved := c.scope.chain
+		c.scope.chain = &[]int{}
+		c.expression(n.Expression)
+		for _, jump := range *c.scope.chain {
+			c.patch(jump)
+		}
+		c.scope.chain = saved
+	case *ast.CallExpression:
+		c.call(n)
+	case *ast.NewExpression:
+		c.expression(n.Callee)
+		c.elements(n.Arguments)
+		c.emitCall(n.Pos(), code.OpNew, c.name(n.Callee.String()), c.arguments(n.Arguments))
+
+	default:
+		c.errorf("cannot compile %T", n)
+	}
+}
+
+// elements compiles the elements of an array literal or the arguments of a
+// call. A spread element is marked with OpSpread for the instruction that
+// collects them to expand.
+func (c *Compiler) elements(elements []ast.Expression) {
+	for _, e := range elements {
+		spread, ok := e.(*ast.SpreadElement)
+		if !ok {
+			c.expression(e)
+			continue
+		}
+		c.expression(spread.Argument)
+		restore := c.at(spread)
+		c.emit(code.OpSpread)
+		restore()
+	}
+}
+
+func (c *Compiler) arguments(args []ast.Expression) int {
+	return c.count(len(args), 0xff, "arguments")
+}
+
+// count returns n, the number of values an instruction collects, if it is
+// at most max.
+func (c *Compiler) count(n, max int, what string) int {
+	if n > max {
+		c.errorf("too many %s", what)
+	}
+	return n
+}
+
+func (c *Compiler) hashLiteral(n *ast.HashLiteral) {
+	keys := n.Keys
+	if keys == nil {
+		for key := range n.Pairs {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		if spread, ok := key.(*ast.SpreadElement); ok {
+			c.emit(code.OpNil)
+			c.expression(spread.Argument)
+			continue
+		}
+		c.expression(key)
+		c.expression(n.Pairs[key])
+	}
+	c.emit(code.OpHash, c.count(len(keys), 0xffff, "object properties"))
+}
+
+// optional ends the optional chain if the link just compiled is optional
+// and its value nullish. drop is the number of values below it to discard.
+func (c *Compiler) optional(optional bool, drop int) {
+	if !optional {
+		return
+	}
+	if c.scope.chain == nil {
+		c.errorf("optional link outside of an optional chain")
+	}
+	*c.scope.chain = append(*c.scope.chain, c.jump(code.OpJumpIfNullish, drop))
+}
+
+// call compiles a call. Calling a member, obj.method() or obj["method"](),
+// passes obj as this.
+func (c *Compiler) call(n *ast.CallExpression) {
+	op := code.OpCall
+	drop := 0
+	switch callee := n.Function.(type) {
+	case *ast.MemberExpression:
+		restore := c.at(callee)
+		c.expression(callee.Object)
+		c.optional(callee.Optional, 0)
+		c.emit(code.OpDup)
+		c.emit(code.OpMember, c.name(callee.Property.Value))
+		restore()
+		op, drop = code.OpCallMethod, 1
+	case *ast.IndexExpression:
+		restore := c.at(callee)
+		c.expression(callee.Left)
+		c.optional(callee.Optional, 0)
+		c.emit(code.OpDup)
+		c.expression(callee.Index)
+		c.emit(code.OpIndex)
+		restore()
+		op, drop = code.OpCallMethod, 1
+	default:
+		c.expression(callee)
+	}
+	c.optional(n.Optional, drop)
+	c.elements(n.Arguments)
+	c.emitCall(n.Function.Pos(), op, c.arguments(n.Arguments))
+}
+
+func (c *Compiler) assign(n *ast.AssignExpression) {
+	compound := n.Operator != "="
+	var operator int
+	if compound {
+		// a += b is a = a + b
+		operator = c.operator(n.Operator[:len(n.Operator)-1])
+	}
+
+	switch target := n.Target.(type) {
+	case *ast.Identifier:
+		name := c.variable(target.Value)
+		if compound {
+			c.emit(code.OpGetName, name)
+		}
+		c.expression(n.Value)
+		if compound {
+			c.emit(code.OpBinary, operator)
+		}
+		c.emit(code.OpAssignName, name)
+
+	case *ast.MemberExpression:
+		name := c.name(target.Property.Value)
+		c.expression(target.Object)
+		if compound {
+			c.emit(code.OpDup)
+			c.emit(code.OpMemberRef, name)
+		}
+		c.expression(n.Value)
+		if compound {
+			c.emit(code.OpBinary, operator)
+		}
+		c.emit(code.OpSetMember, name)
+
+	case *ast.IndexExpression:
+		c.expression(target.Left)
+		c.expression(target.Index)
+		if compound {
+			c.emit(code.OpDup2)
+			c.emit(code.OpIndexRef)
+		}
+		c.expression(n.Value)
+		if compound {
+			c.emit(code.OpBinary, operator)
+		}
+		c.emit(code.OpSetIndex)
+
+	default:
+		c.errorf("invalid assignment target")
+	}
+}
+
+func (c *Compiler) update(n *ast.UpdateExpression) {
+	flags := 0
+	if n.Operator == "--" {
+		flags |= code.UpdateDecrement
+	}
+	if n.Prefix {
+		flags |= code.UpdatePrefix
+	}
+
+	switch target := n.Target.(type) {
+	case *ast.Identifier:
+		c.emit(code.OpUpdateName, c.variable(target.Value), flags)
+	case *ast.MemberExpression:
+		c.expression(target.Object)
+		c.emit(code.OpUpdateMember, c.name(target.Property.Value), flags)
+	case *ast.IndexExpression:
+		c.expression(target.Left)
+		c.expression(target.Index)
+		c.emit(code.OpUpdateIndex, flags)
+	default:
+		c.errorf("invalid assignment target")
+	}
+}
+
+// function compiles a function literal to a constant and emits the
+// instruction that creates a closure of it. A named function expression
+// binds its own name in a scope of its own.
+func (c *Compiler) function(fn *ast.FunctionLiteral, named bool) {
+	compiled := &object.CompiledFunction{
+		Name:  fn.Name,
+		Arrow: fn.Arrow,
+		Source: (&object.Function{
+			Token:      fn.Token,
+			Parameters: fn.Parameters,
+			Defaults:   fn.Defaults,
+			Rest:       fn.Rest,
+			Body:       fn.Body,
+			Arrow:      fn.Arrow,
+		}).Inspect(),
+	}
+	for idx, param := range fn.Parameters {
+		compiled.Parameters = append(compiled.Parameters, param.Value)
+		if compiled.Bound == idx && (idx >= len(fn.Defaults) || fn.Defaults[idx] == nil) {
+			compiled.Bound++
+		}
+	}
+	if fn.Rest != nil {
+		compiled.Rest = fn.Rest.Value
+	}
+
+	c.enter(compiled)
+	// The virtual machine binds the parameters up to the first default. The
+	// rest are bound here one at a time, so each default sees only the
+	// parameters before it; a default replaces an undefined argument.
+	for idx := compiled.Bound; idx < len(fn.Parameters); idx++ {
+		c.emit(code.OpArgument, idx)
+		if idx < len(fn.Defaults) && fn.Defaults[idx] != nil {
+			c.emit(code.OpDup)
+			skip := c.jump(code.OpJumpIfNotUndefined)
+			c.emit(code.OpPop)
+			c.expression(fn.Defaults[idx])
+			c.patch(skip)
+		}
+		c.emit(code.OpDefine, c.name(fn.Parameters[idx].Value))
+		c.emit(code.OpPop)
+	}
+	c.block(fn.Body)
+	c.emit(code.OpCompletion)
+	c.emit(code.OpReturn)
+	c.leave()
+
+	isNamed := 0
+	if named {
+		isNamed = 1
+	}
+	c.emit(code.OpClosure, c.constant(compiled), isNamed)
+}
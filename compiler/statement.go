@@ -0,0 +1,280 @@
+package compiler
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/code"
+	"github.com/bundgaard/js/token"
+)
+
+// statement compiles a statement, which leaves the stack as it found it
+// and sets the completion value the way eval.Eval evaluates the statement.
+func (c *Compiler) statement(n ast.Statement) {
+	defer c.at(n)()
+
+	switch n := n.(type) {
+	case *ast.ExpressionStatement:
+		c.expression(n.Expression)
+		c.complete()
+	case *ast.VariableStatement:
+		if n.Value == nil {
+			// eval.Declare takes nil for a declaration without a value.
+			c.emit(code.OpNil)
+		} else {
+			c.expression(n.Value)
+		}
+		c.emit(code.OpDeclare, c.name(n.Name.Value), int(n.Token.Type))
+		c.emit(code.OpNil)
+		c.complete()
+	case *ast.FunctionDeclaration:
+		c.function(n.Function, false)
+		c.emit(code.OpDefine, c.name(n.Function.Name))
+		c.complete()
+	case *ast.BlockStatement:
+		if len(n.Statements) == 0 {
+			c.emit(code.OpNil)
+			c.complete()
+		}
+		c.block(n)
+	case *ast.IfStatement:
+		c.ifStatement(n)
+	case *ast.WhileStatement:
+		c.whileStatement(n)
+	case *ast.ForStatement:
+		c.forStatement(n)
+	case *ast.ForInStatement:
+		c.forInStatement(n)
+	case *ast.BreakStatement:
+		c.breakStatement(n)
+	case *ast.ContinueStatement:
+		c.continueStatement(n)
+	case *ast.ReturnStatement:
+		c.returnStatement(n)
+	case *ast.ThrowStatement:
+		c.expression(n.Argument)
+		c.emit(code.OpThrow)
+	case *ast.TryStatement:
+		c.tryStatement(n)
+	default:
+		c.errorf("cannot compile %T", n)
+	}
+}
+
+// block compiles the statements of a block. Like eval.Eval it gives the
+// block a scope of its own, but only when something is declared in it.
+func (c *Compiler) block(block *ast.BlockStatement) {
+	scoped := needsScope(block)
+	if scoped {
+		c.emit(code.OpPushScope)
+		c.push(&control{kind: controlScope})
+	}
+	for _, statement := range block.Statements {
+		c.statement(statement)
+	}
+	if scoped {
+		c.pop()
+		c.emit(code.OpPopScope)
+	}
+}
+
+// needsScope reports whether block declares something in its own scope. var
+// declarations are bound in the function scope, so they do not count.
+func needsScope(block *ast.BlockStatement) bool {
+	for _, statement := range block.Statements {
+		switch statement := statement.(type) {
+		case *ast.VariableStatement:
+			if statement.Token.Type == token.Let || statement.Token.Type == token.Const {
+				return true
+			}
+		case *ast.FunctionDeclaration:
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Compiler) ifStatement(n *ast.IfStatement) {
+	c.emit(code.OpNil)
+	c.complete()
+	c.expression(n.Condition)
+	otherwise := c.jump(code.OpJumpIfFalse)
+	c.block(n.Consequence)
+	if n.Alternative == nil {
+		c.patch(otherwise)
+		return
+	}
+	end := c.jump(code.OpJump)
+	c.patch(otherwise)
+	if block, ok := n.Alternative.(*ast.BlockStatement); ok {
+		c.block(block)
+	} else {
+		c.statement(n.Alternative)
+	}
+	c.patch(end)
+}
+
+func (c *Compiler) whileStatement(n *ast.WhileStatement) {
+	start := c.here()
+	c.expression(n.Condition)
+	exit := c.jump(code.OpJumpIfFalse)
+
+	loop := &control{kind: controlLoop}
+	c.push(loop)
+	c.block(n.Body)
+	c.pop()
+	c.jumpBack(start)
+
+	c.patch(exit)
+	c.exitLoop(loop, start)
+	c.emit(code.OpNil)
+	c.complete()
+}
+
+func (c *Compiler) forStatement(n *ast.ForStatement) {
+	// let and const declarations get a scope, which every iteration copies
+	// so closures keep the bindings of their own iteration.
+	perIteration := false
+	if v, ok := n.Init.(*ast.VariableStatement); ok {
+		perIteration = v.Token.Type == token.Let || v.Token.Type == token.Const
+	}
+	if perIteration {
+		c.emit(code.OpPushScope)
+		c.push(&control{kind: controlScope})
+	}
+	if n.Init != nil {
+		c.statement(n.Init)
+	}
+
+	start := c.here()
+	exit := -1
+	if n.Condition != nil {
+		c.expression(n.Condition)
+		exit = c.jump(code.OpJumpIfFalse)
+	}
+
+	loop := &control{kind: controlLoop}
+	c.push(loop)
+	c.block(n.Body)
+	c.pop()
+
+	next := c.here()
+	if perIteration {
+		c.emit(code.OpCopyScope)
+	}
+	if n.Update != nil {
+		c.expression(n.Update)
+		c.emit(code.OpPop)
+	}
+	c.jumpBack(start)
+
+	if exit >= 0 {
+		c.patch(exit)
+	}
+	c.exitLoop(loop, next)
+	if perIteration {
+		c.pop()
+		c.emit(code.OpPopScope)
+	}
+	c.emit(code.OpNil)
+	c.complete()
+}
+
+func (c *Compiler) forInStatement(n *ast.ForInStatement) {
+	c.expression(n.Iterable)
+	of := 0
+	if n.Of {
+		of = 1
+	}
+	c.emit(code.OpIter, of)
+	c.push(&control{kind: controlValue})
+
+	start := c.here()
+	exit := c.jump(code.OpIterNext)
+
+	loop := &control{kind: controlLoop}
+	c.push(loop)
+	// Every iteration binds the variable in a scope of its own.
+	c.emit(code.OpPushScope)
+	c.push(&control{kind: controlScope})
+	if n.Declaration != nil {
+		c.emit(code.OpDeclare, c.name(n.Name.Value), int(n.Declaration.Type))
+	} else {
+		c.emit(code.OpAssignName, c.variable(n.Name.Value))
+		c.emit(code.OpPop)
+	}
+	c.block(n.Body)
+	c.pop()
+	c.emit(code.OpPopScope)
+	c.pop()
+	c.jumpBack(start)
+
+	c.patch(exit)
+	c.exitLoop(loop, start)
+	c.pop()
+	c.emit(code.OpPop)
+	c.emit(code.OpNil)
+	c.complete()
+}
+
+// exitLoop patches the breaks of loop to the next instruction and its
+// continues to next.
+func (c *Compiler) exitLoop(loop *control, next int) {
+	for _, jump := range loop.breaks {
+		c.patch(jump)
+	}
+	for _, jump := range loop.continues {
+		c.patchTo(jump, next)
+	}
+}
+
+func (c *Compiler) tryStatement(n *ast.TryStatement) {
+	c.emit(code.OpNil)
+	c.complete()
+
+	// An error in the try or catch block runs the finally block and is
+	// thrown again.
+	var rethrow int
+	if n.Finalizer != nil {
+		rethrow = c.jump(code.OpTry)
+		c.push(&control{kind: controlFinally, finalizer: n.Finalizer})
+	}
+
+	if n.Handler == nil {
+		c.block(n.Block)
+	} else {
+		handler := c.jump(code.OpTry)
+		c.push(&control{kind: controlHandler})
+		c.block(n.Block)
+		c.pop()
+		c.emit(code.OpEndTry)
+		end := c.jump(code.OpJump)
+
+		c.patch(handler)
+		c.emit(code.OpCatch)
+		c.emit(code.OpPushScope)
+		c.push(&control{kind: controlScope})
+		if n.Param != nil {
+			c.emit(code.OpDefine, c.name(n.Param.Value))
+		}
+		c.emit(code.OpPop)
+		c.emit(code.OpNil)
+		c.complete()
+		c.block(n.Handler)
+		c.pop()
+		c.emit(code.OpPopScope)
+		c.patch(end)
+	}
+
+	if n.Finalizer != nil {
+		c.pop()
+		c.emit(code.OpEndTry)
+		c.finally(n.Finalizer)
+		end := c.jump(code.OpJump)
+
+		c.patch(rethrow)
+		c.push(&control{kind: controlValue})
+		c.finally(n.Finalizer)
+		c.pop()
+		c.emit(code.OpThrow)
+		c.patch(end)
+	}
+}
@@ -2,32 +2,123 @@ package eval
 
 import "github.com/bundgaard/js/object"
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// CallClosure calls a function compiled by package compiler, held to
+// budget. Package vm, which runs compiled functions, sets it; a closure
+// cannot exist without it.
+var CallClosure func(closure *object.Closure, this object.Object, args []object.Object, budget *object.Budget) object.Object
+
+// applyFunction calls fn. A function is held to budget, the budget of the
+// run calling it, rather than to that of the run which created it. Without
+// one the call starts a budget from object.NewBudget, so even then runaway
+// recursion ends with a RangeError rather than a Go stack overflow.
+func applyFunction(fn object.Object, this object.Object, args []object.Object, budget *object.Budget) object.Object {
 	switch fn := fn.(type) {
 	case *object.BuiltinObject:
-		return fn.Fn(args...)
+		return object.Normalize(fn.Fn(args...))
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		if budget == nil {
+			budget = object.NewBudget()
+		}
+		if err := budget.Enter(); err != nil {
+			return err
+		}
+		defer budget.Leave()
+		extendedEnv, err := extendFunctionEnv(fn, args, budget)
+		if err != nil {
+			return err
+		}
+		if !fn.Arrow {
+			// A plain call has no receiver; bind this anyway so it does not
+			// resolve to the this of an enclosing function.
+			if this == nil {
+				this = &object.NullObject{}
+			}
+			extendedEnv.Set("this", this)
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
+	case *object.Closure:
+		return CallClosure(fn, this, args, budget)
 
 	default:
-		return newError("not function: %q", fn.Type())
+		return newTypeError("not function: %q", fn.Type())
 	}
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
-	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue.Value
+	switch v := obj.(type) {
+	case *object.ReturnValue:
+		obj = v.Value
+	case *object.Break:
+		return newError("illegal break statement")
+	case *object.Continue:
+		return newError("illegal continue statement")
+	}
+	if obj == nil {
+		// A function that ends without a value returns undefined.
+		return &object.UndefinedObject{}
 	}
 	return obj
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// extendFunctionEnv binds the parameters of fn to args in a new scope.
+// Missing arguments are undefined unless the parameter has a default, the
+// rest parameter collects the arguments left over and, except in arrow
+// functions, arguments holds them all.
+func extendFunctionEnv(fn *object.Function, args []object.Object, budget *object.Budget) (*object.Environment, object.Object) {
 	env := object.NewEnclosedEnvironment(fn.Environment)
+	env.SetBudget(budget)
+	if !fn.Arrow {
+		env.Set("arguments", &object.Array{Elements: append([]object.Object{}, args...)})
+	}
+
 	for idx, param := range fn.Parameters {
-		env.Set(param.Value, args[idx])
+		var value object.Object = &object.UndefinedObject{}
+		if idx < len(args) {
+			value = args[idx]
+		}
+		// Defaults see the parameters before them.
+		if idx < len(fn.Defaults) && fn.Defaults[idx] != nil && value.Type() == object.UndefinedType {
+			value = Eval(fn.Defaults[idx], env)
+			if isError(value) {
+				return nil, value
+			}
+		}
+		env.Set(param.Value, value)
+	}
+
+	if fn.Rest != nil {
+		rest := &object.Array{Elements: []object.Object{}}
+		if len(args) > len(fn.Parameters) {
+			rest.Elements = append(rest.Elements, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, rest)
+	}
+	return env, nil
+}
+
+// functionName is the name of a script function in stack traces.
+func functionName(fn object.Object) string {
+	name := ""
+	switch fn := fn.(type) {
+	case *object.Function:
+		name = fn.Name
+	case *object.Closure:
+		name = fn.Function.Name
+	}
+	if name == "" {
+		return "<anonymous>"
 	}
-	return env
+	return name
+}
 
+// isArrowFunction reports whether fn is a script arrow function.
+func isArrowFunction(fn object.Object) bool {
+	switch fn := fn.(type) {
+	case *object.Function:
+		return fn.Arrow
+	case *object.Closure:
+		return fn.Function.Arrow
+	}
+	return false
 }
@@ -0,0 +1,188 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+)
+
+// reference is an assignable location: a variable, an array slot or a hash
+// entry. Its sub-expressions are evaluated once when the reference is
+// created, so a[i()] += 1 calls i only once.
+type reference struct {
+	get func() object.Object
+	set func(value object.Object) object.Object
+}
+
+func evalReference(target ast.Expression, environment *object.Environment) (*reference, object.Object) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		return &reference{
+			get: func() object.Object {
+				return evalIdentifier(target, environment)
+			},
+			set: func(value object.Object) object.Object {
+				if err := environment.Assign(target.Value, value); err != nil {
+					return err
+				}
+				return value
+			},
+		}, nil
+
+	case *ast.IndexExpression:
+		left := Eval(target.Left, environment)
+		if isError(left) {
+			return nil, left
+		}
+		index := Eval(target.Index, environment)
+		if isError(index) {
+			return nil, index
+		}
+		return indexReference(left, index, environment)
+
+	case *ast.MemberExpression:
+		obj := Eval(target.Object, environment)
+		if isError(obj) {
+			return nil, obj
+		}
+		return propertyReference(obj, target.Property.Value)
+	}
+
+	return nil, newError("invalid assignment target")
+}
+
+// maxArrayGap is how many elements past its end an assignment may grow an
+// array by.
+const maxArrayGap = 1 << 20
+
+func indexReference(left, index object.Object, environment *object.Environment) (*reference, object.Object) {
+	switch left := left.(type) {
+	case *object.Array:
+		n, ok := index.(*object.NumberObject)
+		if !ok {
+			return nil, newTypeError("array index must be a number, got %s", index.Type())
+		}
+		idx, ok := arrayIndex(n)
+		if !ok {
+			return nil, newError("invalid array index %s", n.Inspect())
+		}
+		return &reference{
+			get: func() object.Object {
+				return evalArrayIndexExpression(left, index)
+			},
+			set: func(value object.Object) object.Object {
+				if idx >= len(left.Elements) {
+					// Arrays are dense, so the elements up to idx are
+					// stored as undefined. Far past the end that would
+					// take more memory than the script could use.
+					if idx-len(left.Elements) >= maxArrayGap {
+						return newRangeError("array index %d is too far past the end of an array of length %d", idx, len(left.Elements))
+					}
+					if err := allocate(environment, idx+1-len(left.Elements)); err != nil {
+						return err
+					}
+				}
+				for len(left.Elements) <= idx {
+					left.Elements = append(left.Elements, &object.UndefinedObject{})
+				}
+				left.Elements[idx] = value
+				return value
+			},
+		}, nil
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return nil, newTypeError("unusable as hash key: %q", index.Type())
+		}
+		return &reference{
+			get: func() object.Object {
+				return evalHashIndexExpression(left, index)
+			},
+			set: func(value object.Object) object.Object {
+				if _, ok := left.Pairs[key.HashKey()]; !ok {
+					if err := allocate(environment, 1); err != nil {
+						return err
+					}
+				}
+				left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: value}
+				return value
+			},
+		}, nil
+	}
+
+	return nil, newTypeError("cannot assign to index of %s", inspect(left))
+}
+
+func evalAssignExpression(node *ast.AssignExpression, environment *object.Environment) object.Object {
+	ref, err := evalReference(node.Target, environment)
+	if err != nil {
+		return err
+	}
+
+	var current object.Object
+	if node.Operator != "=" {
+		current = ref.get()
+		if isError(current) {
+			return current
+		}
+	}
+
+	value := Eval(node.Value, environment)
+	if isError(value) {
+		return value
+	}
+
+	if node.Operator != "=" {
+		// a += b is a = a + b
+		operator := node.Operator[:len(node.Operator)-1]
+		value = evalInfixExpression(operator, current, value, environment)
+		if isError(value) {
+			return value
+		}
+	}
+	return ref.set(value)
+}
+
+func evalUpdateExpression(node *ast.UpdateExpression, environment *object.Environment) object.Object {
+	ref, err := evalReference(node.Target, environment)
+	if err != nil {
+		return err
+	}
+	return update(node.Operator, node.Prefix, ref)
+}
+
+// update applies ++ or -- to ref and returns the value of the expression.
+func update(operator string, prefix bool, ref *reference) object.Object {
+	current := ref.get()
+	if isError(current) {
+		return current
+	}
+	updated, result := increment(operator, prefix, current)
+	if isError(updated) {
+		return updated
+	}
+	if err := ref.set(updated); isError(err) {
+		return err
+	}
+	return result
+}
+
+// increment returns the value ++ or -- stores for current and the value of
+// the expression.
+func increment(operator string, prefix bool, current object.Object) (updated, result object.Object) {
+	n, ok := toNumber(current)
+	if !ok {
+		err := newTypeError("invalid operand for %s: %s", operator, current.Type())
+		return err, err
+	}
+
+	value := n + 1
+	if operator == "--" {
+		value = n - 1
+	}
+	updated = &object.NumberObject{Value: value}
+	if prefix {
+		return updated, updated
+	}
+	return updated, &object.NumberObject{Value: n}
+}
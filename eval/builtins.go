@@ -1,21 +1,15 @@
 package eval
 
 import (
-	"fmt"
 	"github.com/bundgaard/js/object"
+	"math"
 	"os"
 )
 
+// builtins is never changed after initialization and its functions hold no
+// state, so runs on any number of goroutines may share it.
 var builtins = map[string]*object.BuiltinObject{
-	"println": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) == 1 {
-				fmt.Fprintf(os.Stdout, "%v\n", args[0].Inspect())
-			}
-
-			return &object.NullObject{}
-		},
-	},
+	"println": Println(os.Stdout),
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
@@ -24,10 +18,30 @@ var builtins = map[string]*object.BuiltinObject{
 
 			switch arg := args[0].(type) {
 			case *object.StringObject:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.NumberObject{Value: float64(len(arg.Value))}
 			default:
 				return newError("argument to %q not supported, got %q", "len", args[0].Type())
 			}
 		},
 	},
+	"Error":          errorConstructor("Error"),
+	"TypeError":      errorConstructor("TypeError"),
+	"RangeError":     errorConstructor("RangeError"),
+	"ReferenceError": errorConstructor("ReferenceError"),
+}
+
+// globalConstant returns the value of the global constants NaN, Infinity
+// and undefined, and the default console.
+func globalConstant(name string) (object.Object, bool) {
+	switch name {
+	case "console":
+		return defaultConsole(), true
+	case "undefined":
+		return &object.UndefinedObject{}, true
+	case "NaN":
+		return &object.NumberObject{Value: math.NaN()}, true
+	case "Infinity":
+		return &object.NumberObject{Value: math.Inf(1)}, true
+	}
+	return nil, false
 }
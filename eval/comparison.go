@@ -0,0 +1,254 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/object"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func isComparisonOperator(operator string) bool {
+	switch operator {
+	case "==", "!=", "===", "!==", "<", ">", "<=", ">=":
+		return true
+	}
+	return false
+}
+
+func nativeBoolToBooleanObject(value bool) *object.Boolean {
+	return &object.Boolean{Value: value}
+}
+
+func evalComparisonExpression(operator string, left, right object.Object) object.Object {
+	switch operator {
+	case "===":
+		return nativeBoolToBooleanObject(strictEquals(left, right))
+	case "!==":
+		return nativeBoolToBooleanObject(!strictEquals(left, right))
+	case "==":
+		return nativeBoolToBooleanObject(looseEquals(left, right))
+	case "!=":
+		return nativeBoolToBooleanObject(!looseEquals(left, right))
+	}
+
+	if l, ok := left.(*object.StringObject); ok {
+		if r, ok := right.(*object.StringObject); ok {
+			return nativeBoolToBooleanObject(compareOrdered(operator, float64(strings.Compare(l.Value, r.Value)), 0))
+		}
+	}
+
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if !lok || !rok {
+		// Objects convert to NaN; every relational comparison with NaN is
+		// false.
+		return nativeBoolToBooleanObject(false)
+	}
+	return nativeBoolToBooleanObject(compareOrdered(operator, l, r))
+}
+
+func compareOrdered(operator string, left, right float64) bool {
+	switch operator {
+	case "<":
+		return left < right
+	case ">":
+		return left > right
+	case "<=":
+		return left <= right
+	case ">=":
+		return left >= right
+	}
+	return false
+}
+
+// strictEquals implements ===: values of different types are never equal,
+// primitives compare by value and everything else by identity.
+func strictEquals(left, right object.Object) bool {
+	if left.Type() != right.Type() {
+		return false
+	}
+
+	switch l := left.(type) {
+	case *object.NumberObject:
+		return l.Value == right.(*object.NumberObject).Value
+	case *object.StringObject:
+		return l.Value == right.(*object.StringObject).Value
+	case *object.Boolean:
+		return l.Value == right.(*object.Boolean).Value
+	case *object.NullObject, *object.UndefinedObject:
+		return true
+	default:
+		return left == right
+	}
+}
+
+// looseEquals implements ==, converting numbers, strings and booleans to
+// numbers when the operand types differ.
+func looseEquals(left, right object.Object) bool {
+	if left.Type() == right.Type() {
+		return strictEquals(left, right)
+	}
+
+	// null and undefined only equal each other
+	if isNullish(left) || isNullish(right) {
+		return isNullish(left) && isNullish(right)
+	}
+
+	if !isPrimitive(left) || !isPrimitive(right) {
+		return false
+	}
+
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	return lok && rok && l == r
+}
+
+func isPrimitive(obj object.Object) bool {
+	switch obj.Type() {
+	case object.NumberType, object.StringType, object.BooleanType, object.NullType, object.UndefinedType:
+		return true
+	}
+	return false
+}
+
+// toNumber converts a primitive to a number. Strings that do not spell a
+// number convert to NaN. The second result is false for non-primitives.
+func toNumber(obj object.Object) (float64, bool) {
+	switch v := obj.(type) {
+	case *object.NumberObject:
+		return v.Value, true
+	case *object.Boolean:
+		if v.Value {
+			return 1, true
+		}
+		return 0, true
+	case *object.NullObject:
+		return 0, true
+	case *object.UndefinedObject:
+		return math.NaN(), true
+	case *object.StringObject:
+		return stringToNumber(v.Value), true
+	}
+	return 0, false
+}
+
+// isNumeric reports whether + treats obj as a number: a primitive other than a
+// string.
+func isNumeric(obj object.Object) bool {
+	return isPrimitive(obj) && obj.Type() != object.StringType
+}
+
+// toNumeric converts obj to a number for arithmetic. Objects convert through
+// their string form, so [5] is 5 and {} is NaN.
+func toNumeric(obj object.Object) float64 {
+	if n, ok := toNumber(obj); ok {
+		return n
+	}
+	return stringToNumber(toString(obj))
+}
+
+// toString converts obj to a string the way String(obj) does: arrays join
+// their elements with commas, errors are name: message and other objects are
+// [object Object].
+func toString(obj object.Object) string {
+	return string(appendString(nil, obj, map[*object.Array]bool{}))
+}
+
+// appendString appends the string form of obj to b. An array that contains
+// itself, which seen tracks, converts to the empty string there.
+func appendString(b []byte, obj object.Object, seen map[*object.Array]bool) []byte {
+	switch v := obj.(type) {
+	case *object.Array:
+		if seen[v] {
+			return b
+		}
+		seen[v] = true
+		for i, e := range v.Elements {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if e := object.Normalize(e); e != nil && !isNullish(e) {
+				b = appendString(b, e, seen)
+			}
+		}
+		delete(seen, v)
+		return b
+	case *object.Hash:
+		if isErrorObject(v) {
+			name, _ := v.GetProperty("name")
+			b = append(b, inspect(name)...)
+			if message, _ := v.GetProperty("message"); inspect(message) != "" {
+				b = append(b, ": "+inspect(message)...)
+			}
+			return b
+		}
+		return append(b, "[object Object]"...)
+	}
+	return append(b, inspect(obj)...)
+}
+
+// stringToNumber converts s the way Number(s) does.
+func stringToNumber(s string) float64 {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "":
+		return 0
+	case "Infinity", "+Infinity":
+		return math.Inf(1)
+	case "-Infinity":
+		return math.Inf(-1)
+	}
+
+	if len(s) > 2 && s[0] == '0' {
+		base := 0
+		switch s[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 0 {
+			n, err := strconv.ParseUint(s[2:], base, 64)
+			if err != nil {
+				return math.NaN()
+			}
+			return float64(n)
+		}
+	}
+
+	// ParseFloat also accepts spellings JavaScript does not, such as
+	// "inf", hex floats and digit separators.
+	for _, c := range s {
+		if !('0' <= c && c <= '9') && c != '.' && c != 'e' && c != 'E' && c != '+' && c != '-' {
+			return math.NaN()
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return f
+		}
+		return math.NaN()
+	}
+	return f
+}
+
+// isTruthy reports whether obj counts as true in a condition.
+func isTruthy(obj object.Object) bool {
+	switch v := obj.(type) {
+	case nil:
+		return false
+	case *object.Boolean:
+		return v.Value
+	case *object.NullObject, *object.UndefinedObject:
+		return false
+	case *object.NumberObject:
+		return v.Value != 0 && !math.IsNaN(v.Value)
+	case *object.StringObject:
+		return v.Value != ""
+	default:
+		return true
+	}
+}
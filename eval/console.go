@@ -0,0 +1,44 @@
+package eval
+
+import (
+	"fmt"
+	"github.com/bundgaard/js/object"
+	"io"
+	"os"
+	"strings"
+)
+
+// Println returns a println builtin that writes its arguments to w,
+// separated by spaces and followed by a newline.
+func Println(w io.Writer) *object.BuiltinObject {
+	return &object.BuiltinObject{
+		Fn: func(args ...object.Object) object.Object {
+			words := make([]string, len(args))
+			for i, arg := range args {
+				words[i] = inspect(arg)
+			}
+			fmt.Fprintln(w, strings.Join(words, " "))
+			return &object.UndefinedObject{}
+		},
+	}
+}
+
+// Console returns a console object. Its log, info and debug methods print
+// like println to stdout, warn and error to stderr.
+func Console(stdout, stderr io.Writer) *object.Hash {
+	console := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+	for _, method := range []string{"log", "info", "debug"} {
+		console.SetProperty(method, Println(stdout))
+	}
+	for _, method := range []string{"warn", "error"} {
+		console.SetProperty(method, Println(stderr))
+	}
+	return console
+}
+
+// defaultConsole is the console of environments that do not declare one.
+// A new object is made for every use, so scripts cannot change it for
+// others.
+func defaultConsole() *object.Hash {
+	return Console(os.Stdout, os.Stderr)
+}
@@ -0,0 +1,59 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+	"github.com/bundgaard/js/token"
+)
+
+func evalVariableStatement(node *ast.VariableStatement, environment *object.Environment) object.Object {
+	if node.Value == nil {
+		return declare(node.Token.Type, node.Name.Value, nil, environment)
+	}
+	value := Eval(node.Value, environment)
+	if value == nil {
+		value = &object.UndefinedObject{}
+	}
+	if isError(value) {
+		return value
+	}
+
+	return declare(node.Token.Type, node.Name.Value, value, environment)
+}
+
+// declare binds name the way the declaration keyword kind does: let and const
+// bind in the current block, var in the enclosing function scope. A nil
+// value is a declaration without an initializer, which binds undefined;
+// var x; leaves an existing x alone.
+func declare(kind token.Type, name string, value object.Object, environment *object.Environment) object.Object {
+	if value == nil {
+		if scope := environment.FunctionScope(); kind == token.Var && scope.Has(name) {
+			return nil
+		}
+		value = &object.UndefinedObject{}
+	}
+	switch kind {
+	case token.Let, token.Const:
+		if environment.Has(name) {
+			return newError("identifier %q has already been declared", name)
+		}
+		if kind == token.Const {
+			environment.SetConst(name, value)
+		} else {
+			environment.SetLexical(name, value)
+		}
+	default:
+		scope := environment.FunctionScope()
+		if scope.IsLexical(name) {
+			return newError("identifier %q has already been declared", name)
+		}
+		scope.Set(name, value)
+	}
+	return nil
+}
+
+// isLexicalDeclaration reports whether stmt is a let or const declaration.
+func isLexicalDeclaration(stmt ast.Statement) bool {
+	v, ok := stmt.(*ast.VariableStatement)
+	return ok && (v.Token.Type == token.Let || v.Token.Type == token.Const)
+}
@@ -2,6 +2,7 @@ package eval
 
 import (
 	"fmt"
+	"github.com/bundgaard/js/ast"
 	"github.com/bundgaard/js/object"
 )
 
@@ -14,3 +15,42 @@ func isError(obj object.Object) bool {
 func newError(format string, v ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, v...)}
 }
+
+// newTypeError returns a TypeError, raised when a value has the wrong type
+// for an operation.
+func newTypeError(format string, v ...interface{}) *object.Error {
+	err := newError(format, v...)
+	err.Name = "TypeError"
+	return err
+}
+
+// newReferenceError returns a ReferenceError, raised when a name is not
+// declared.
+func newReferenceError(format string, v ...interface{}) *object.Error {
+	err := newError(format, v...)
+	err.Name = "ReferenceError"
+	return err
+}
+
+// newRangeError returns a RangeError, raised when a value is out of the
+// range an operation allows.
+func newRangeError(format string, v ...interface{}) *object.Error {
+	err := newError(format, v...)
+	err.Name = "RangeError"
+	return err
+}
+
+// allocate charges size to the budget of environment, if it has one.
+func allocate(environment *object.Environment, size int) *object.Error {
+	if budget := environment.Budget(); budget != nil {
+		return budget.Allocate(size)
+	}
+	return nil
+}
+
+// newErrorAt returns an error raised at node n.
+func newErrorAt(n ast.Node, format string, v ...interface{}) *object.Error {
+	err := newError(format, v...)
+	err.Position = n.Pos()
+	return err
+}
@@ -0,0 +1,42 @@
+package eval
+
+import "github.com/bundgaard/js/object"
+
+// errorConstructor returns the builtin for Error and its subtypes. It
+// creates the error object whether or not it is called with new.
+func errorConstructor(name string) *object.BuiltinObject {
+	return &object.BuiltinObject{
+		Fn: func(args ...object.Object) object.Object {
+			message := ""
+			if len(args) > 0 {
+				if _, ok := args[0].(*object.UndefinedObject); !ok {
+					message = inspect(args[0])
+				}
+			}
+			return newErrorObject(name, message)
+		},
+	}
+}
+
+// newErrorObject returns the object scripts see as an error: a hash with
+// name, message and stack properties.
+func newErrorObject(name, message string) *object.Hash {
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+	hash.SetProperty("name", &object.StringObject{Value: name})
+	hash.SetProperty("message", &object.StringObject{Value: message})
+	hash.SetProperty("stack", &object.StringObject{Value: name + ": " + message})
+	return hash
+}
+
+func isErrorObject(hash *object.Hash) bool {
+	for _, property := range []string{"name", "message", "stack"} {
+		value, ok := hash.GetProperty(property)
+		if !ok {
+			return false
+		}
+		if _, ok := value.(*object.StringObject); !ok {
+			return false
+		}
+	}
+	return true
+}
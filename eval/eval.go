@@ -1,15 +1,26 @@
 package eval
 
 import (
-	"fmt"
 	"github.com/bundgaard/js/ast"
 	"github.com/bundgaard/js/object"
 	"log"
+	"math"
 )
 
+// evalExpressions evaluates a list of call arguments or array elements,
+// expanding spread elements into the values of their argument.
 func evalExpressions(exps []ast.Expression, environment *object.Environment) []object.Object {
 	var result []object.Object
 	for _, e := range exps {
+		if spread, ok := e.(*ast.SpreadElement); ok {
+			values := evalSpreadElement(spread, environment)
+			if len(values) == 1 && isError(values[0]) {
+				return values
+			}
+			result = append(result, values...)
+			continue
+		}
+
 		evaluated := Eval(e, environment)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
@@ -24,7 +35,27 @@ func WithEnvironment(program ast.Node) (object.Object, *object.Environment) {
 	return Eval(program, environment), environment
 }
 
+// Eval evaluates n. An error raised while evaluating n is given the position
+// of the innermost node it came from. A panic is re-raised as a *PanicError;
+// use Run to recover it.
 func Eval(n ast.Node, environment *object.Environment) object.Object {
+	defer annotatePanic(n)
+	if budget := environment.Budget(); budget != nil {
+		if err := budget.Step(); err != nil {
+			if n != nil {
+				err.Position = n.Pos()
+			}
+			return err
+		}
+	}
+	result := evalNode(n, environment)
+	if err, ok := result.(*object.Error); ok && !err.Position.IsValid() && n != nil {
+		err.Position = n.Pos()
+	}
+	return result
+}
+
+func evalNode(n ast.Node, environment *object.Environment) object.Object {
 	// log.Printf("Eval %T %v", n, n)
 	switch v := n.(type) {
 	case *ast.Program:
@@ -33,27 +64,50 @@ func Eval(n ast.Node, environment *object.Environment) object.Object {
 		return Eval(v.Expression, environment)
 	case *ast.BlockStatement:
 		return evalBlockStatement(v, environment)
-
-	case *ast.VariableStatement:
-		value := Eval(v.Value, environment)
-		if value != nil && isError(value) {
+	case *ast.IfStatement:
+		return evalIfStatement(v, environment)
+	case *ast.WhileStatement:
+		return evalWhileStatement(v, environment)
+	case *ast.ForStatement:
+		return evalForStatement(v, environment)
+	case *ast.ForInStatement:
+		return evalForInStatement(v, environment)
+	case *ast.ThrowStatement:
+		return evalThrowStatement(v, environment)
+	case *ast.TryStatement:
+		return evalTryStatement(v, environment)
+	case *ast.BreakStatement:
+		return &object.Break{}
+	case *ast.ContinueStatement:
+		return &object.Continue{}
+	case *ast.ReturnStatement:
+		if v.ReturnValue == nil {
+			return &object.ReturnValue{Value: &object.UndefinedObject{}}
+		}
+		value := Eval(v.ReturnValue, environment)
+		if isError(value) {
 			return value
 		}
+		return &object.ReturnValue{Value: value}
 
-		environment.Set(v.Name.Value, value)
+	case *ast.VariableStatement:
+		return evalVariableStatement(v, environment)
 
 	// expressions
 	case *ast.CallExpression:
-		fn := Eval(v.Function, environment)
+		fn, this := evalCallee(v.Function, environment)
 		if isError(fn) {
 			return fn
 		}
+		if isChainEnd(fn, v.Optional) {
+			return &chainEnd{}
+		}
 
 		args := evalExpressions(v.Arguments, environment)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(fn, args)
+		return Apply(fn, this, args, environment, v.Function.Pos())
 
 	case *ast.InfixExpression:
 		left := Eval(v.Left, environment)
@@ -72,14 +126,35 @@ func Eval(n ast.Node, environment *object.Environment) object.Object {
 
 		return evalInfixExpression(v.Operator, left, right, environment)
 
+	case *ast.AssignExpression:
+		return evalAssignExpression(v, environment)
+	case *ast.UpdateExpression:
+		return evalUpdateExpression(v, environment)
 	case *ast.Identifier:
 		return evalIdentifier(v, environment)
 	case *ast.NumberLiteral:
 		return &object.NumberObject{Value: v.Value}
 	case *ast.StringLiteral:
 		return &object.StringObject{Value: v.Value}
+	case *ast.TemplateLiteral:
+		return evalTemplateLiteral(v, environment)
+	case *ast.PrefixExpression:
+		return evalPrefixExpression(v, environment)
+	case *ast.LogicalExpression:
+		return evalLogicalExpression(v, environment)
+	case *ast.ConditionalExpression:
+		return evalConditionalExpression(v, environment)
+	case *ast.OptionalChain:
+		return evalOptionalChain(v, environment)
 	case *ast.IndexExpression:
 		return evalIndexExpression(v, environment)
+	case *ast.MemberExpression:
+		return evalMemberExpression(v, environment)
+	case *ast.ThisExpression:
+		if this, ok := environment.Get("this"); ok {
+			return this
+		}
+		return &object.NullObject{}
 	case *ast.HashLiteral:
 		return evalHashLiteral(v, environment)
 	case *ast.ArrayLiteral:
@@ -87,21 +162,14 @@ func Eval(n ast.Node, environment *object.Environment) object.Object {
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
-		return &object.Array{Elements: elements}
+		return newArray(elements, environment)
+	case *ast.NewExpression:
+		return evalNewExpression(v, environment)
 	case *ast.FunctionLiteral:
-		params := v.Parameters
-		body := v.Body
-
-		fn := &object.Function{
-			Token:       v.Token,
-			Name:        v.Name,
-			Parameters:  params,
-			Body:        body,
-			Environment: environment,
-		}
-		if v.Name != "" {
-			environment.Set(v.Name, fn)
-		}
+		return evalFunctionLiteral(v, environment)
+	case *ast.FunctionDeclaration:
+		fn := newFunction(v.Function, environment)
+		environment.Set(v.Function.Name, fn)
 		return fn
 
 	case *ast.Boolean:
@@ -119,12 +187,14 @@ func Eval(n ast.Node, environment *object.Environment) object.Object {
 func evalBlockStatement(node *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
+	env = object.NewBlockEnvironment(env)
+
 	for _, statement := range node.Statements {
 		result = Eval(statement, env)
 
 		if result != nil {
-			rt := result.Type()
-			if rt == object.ReturnValueType || rt == object.ErrorType {
+			switch result.Type() {
+			case object.ReturnValueType, object.ErrorType, object.BreakType, object.ContinueType:
 				return result
 			}
 		}
@@ -135,7 +205,26 @@ func evalBlockStatement(node *ast.BlockStatement, env *object.Environment) objec
 func evalHashLiteral(hashLiteral *ast.HashLiteral, environment *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
-	for kn, vn := range hashLiteral.Pairs {
+	keys := hashLiteral.Keys
+	if keys == nil {
+		for kn := range hashLiteral.Pairs {
+			keys = append(keys, kn)
+		}
+	}
+
+	// Keys are evaluated in source order so later entries, spread or not,
+	// override earlier ones.
+	for _, kn := range keys {
+		vn := hashLiteral.Pairs[kn]
+		if spread, ok := kn.(*ast.SpreadElement); ok {
+			value := Eval(spread.Argument, environment)
+			if isError(value) {
+				return value
+			}
+			spreadProperties(value, pairs)
+			continue
+		}
+
 		key := Eval(kn, environment)
 		if key != nil && isError(key) {
 			return key
@@ -143,7 +232,7 @@ func evalHashLiteral(hashLiteral *ast.HashLiteral, environment *object.Environme
 
 		hkey, ok := key.(object.Hashable)
 		if !ok {
-			return &object.Error{Message: fmt.Sprintf("unushable hash key: %q", key.Type())}
+			return newTypeError("unushable hash key: %q", key.Type())
 		}
 
 		value := Eval(vn, environment)
@@ -157,6 +246,9 @@ func evalHashLiteral(hashLiteral *ast.HashLiteral, environment *object.Environme
 		pairs[hashed] = object.HashPair{Key: key, Value: value}
 	}
 
+	if err := allocate(environment, len(pairs)); err != nil {
+		return err
+	}
 	return &object.Hash{Pairs: pairs}
 }
 
@@ -165,19 +257,29 @@ func evalIndexExpression(v *ast.IndexExpression, environment *object.Environment
 	if left != nil && isError(left) {
 		return left
 	}
+	if isChainEnd(left, v.Optional) {
+		return &chainEnd{}
+	}
 
 	index := Eval(v.Index, environment)
 	if index != nil && isError(index) {
 		return index
 	}
 
+	return evalIndex(left, index)
+}
+
+func evalIndex(left, index object.Object) object.Object {
+	index = object.Normalize(index)
 	switch {
+	case left == nil || index == nil:
+		return newTypeError("cannot read index %s of %s", inspect(index), inspect(left))
 	case left.Type() == object.ArrayType && index.Type() == object.NumberType:
 		return evalArrayIndexExpression(left, index)
 	case left.Type() == object.HashType:
 		return evalHashIndexExpression(left, index)
 	default:
-		return &object.Error{Message: fmt.Sprintf("skipping index for now %T %T", left, index)}
+		return newTypeError("skipping index for now %T %T", left, index)
 
 	}
 
@@ -187,41 +289,43 @@ func evalHashIndexExpression(hash object.Object, index object.Object) object.Obj
 	hashObj := hash.(*object.Hash)
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %q", index.Type())
+		return newTypeError("unusable as hash key: %q", index.Type())
 	}
 
 	pair, ok := hashObj.Pairs[key.HashKey()]
 	if !ok {
-		return &object.NullObject{}
+		return &object.UndefinedObject{}
 	}
 
-	return pair.Value
+	return object.Normalize(pair.Value)
 
 }
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
-	idx := index.(*object.NumberObject).Value
-	max := int64(len(arrayObject.Elements) - 1)
-
-	if idx < 0 || idx > max {
-		return &object.NullObject{}
+	idx, ok := arrayIndex(index.(*object.NumberObject))
+	if !ok || idx >= len(arrayObject.Elements) {
+		return &object.UndefinedObject{}
 	}
-	return arrayObject.Elements[idx]
+	return object.Normalize(arrayObject.Elements[idx])
 
 }
 
-func evalStringInfixExpression(operator string, left, right object.Object, env *object.Environment) object.Object {
-	leftVal := left.(*object.StringObject).Value
-	rightVal := right.(*object.StringObject).Value
+// evalConcatenation joins the string forms of left and right.
+func evalConcatenation(left, right object.Object, env *object.Environment) object.Object {
+	leftVal := toString(left)
+	rightVal := toString(right)
+	if err := allocate(env, len(leftVal)+len(rightVal)); err != nil {
+		return err
+	}
 	return &object.StringObject{
 		Value: leftVal + rightVal,
 	}
 }
 
-func evalNumberInfixExpression(operator string, left, right object.Object, env *object.Environment) object.Object {
-	leftVal := left.(*object.NumberObject).Value
-	rightVal := right.(*object.NumberObject).Value
+func evalNumberInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toNumeric(left)
+	rightVal := toNumeric(right)
 	switch operator {
 	case "+":
 		return &object.NumberObject{Value: leftVal + rightVal}
@@ -231,33 +335,39 @@ func evalNumberInfixExpression(operator string, left, right object.Object, env *
 		return &object.NumberObject{Value: leftVal / rightVal}
 	case "*":
 		return &object.NumberObject{Value: leftVal * rightVal}
+	case "%":
+		return &object.NumberObject{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.NumberObject{Value: math.Pow(leftVal, rightVal)}
 	}
-	return nil
+	return newTypeError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 }
 
 func evalInfixExpression(operator string, left, right object.Object, env *object.Environment) object.Object {
+	// Statements evaluate to nil; as operands they are undefined.
+	if left == nil {
+		left = &object.UndefinedObject{}
+	}
+	if right == nil {
+		right = &object.UndefinedObject{}
+	}
+	if isComparisonOperator(operator) {
+		return evalComparisonExpression(operator, left, right)
+	}
 
-	switch {
-	case left.Type() == object.StringType && right.Type() == object.StringType:
-		return evalStringInfixExpression(operator, left, right, env)
-	case left.Type() == object.NumberType && right.Type() == object.NumberType:
-		return evalNumberInfixExpression(operator, left, right, env)
-	case left.Type() != right.Type():
-		return &object.Error{Message: fmt.Sprintf("type mismatch %v %s %v", left.Type(), operator, right.Type())}
-	default:
-		return &object.Error{Message: "unknown operator"}
+	// + joins strings when either operand is a string or an object, which
+	// converts to one; every other case is arithmetic on numbers.
+	if operator == "+" && (!isNumeric(left) || !isNumeric(right)) {
+		return evalConcatenation(left, right, env)
 	}
+	return evalNumberInfixExpression(operator, left, right)
 }
 
 func evalIdentifier(n *ast.Identifier, env *object.Environment) object.Object {
-
-	if val, ok := env.Get(n.Value); ok {
+	if val, ok := Lookup(n.Value, env); ok {
 		return val
 	}
-	if builtin, ok := builtins[n.Value]; ok {
-		return builtin
-	}
-	return newError("identifier %q not found", n.Value)
+	return newReferenceError("identifier %q not found", n.Value)
 }
 
 func evalProgram(n *ast.Program, environment *object.Environment) object.Object {
@@ -269,11 +379,24 @@ func evalProgram(n *ast.Program, environment *object.Environment) object.Object
 
 		switch v := result.(type) {
 		case *object.ReturnValue:
-			return v.Value
+			return newErrorAt(statement, "illegal return statement outside of function")
+		case *object.Break:
+			return newErrorAt(statement, "illegal break statement")
+		case *object.Continue:
+			return newErrorAt(statement, "illegal continue statement")
 		case *object.Error:
-			fmt.Printf("%T %v\n", v, v)
-			continue
+			// An uncaught error ends the program.
+			return v
 		}
 	}
 	return result
 }
+
+// arrayIndex converts n to an array index. Only non-negative integers are
+// valid indexes.
+func arrayIndex(n *object.NumberObject) (int, bool) {
+	if n.Value < 0 || n.Value != math.Trunc(n.Value) || n.Value > math.MaxInt32 {
+		return 0, false
+	}
+	return int(n.Value), true
+}
@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"github.com/bundgaard/js/ast"
 	"github.com/bundgaard/js/object"
 	"github.com/bundgaard/js/parser"
 	"log"
@@ -130,3 +131,879 @@ println(x["hej"]);`)
 	t.Logf("%v %v", output, env)
 
 }
+
+func TestEvalComparison(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected bool
+	}{
+		{`1 < 2`, true},
+		{`2 <= 2`, true},
+		{`3 > 4`, false},
+		{`4 >= 5`, false},
+		{`1 == 1`, true},
+		{`1 != 1`, false},
+		{`"a" < "b"`, true},
+		{`"abc" == "abc"`, true},
+		{`"1" == 1`, true},
+		{`"1" === 1`, false},
+		{`1 !== 2`, true},
+		{`true == 1`, true},
+		{`true === true`, true},
+		{`null == null`, true},
+		{`null == 0`, false},
+		{`"x" < 1`, false},
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test.Input)
+		output, _ := WithEnvironment(p.Parse())
+		result, ok := output.(*object.Boolean)
+		if !ok {
+			t.Errorf("test[%04d] %s: expected boolean. got %v", idx, test.Input, output)
+			continue
+		}
+		if result.Value != test.Expected {
+			t.Errorf("test[%04d] %s: expected %t. got %t", idx, test.Input, test.Expected, result.Value)
+		}
+	}
+}
+
+func TestEvalIfStatement(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`var r = "no"; if (1 < 2) { var r = "yes"; }`, "yes"},
+		{`var r = "no"; if (1 > 2) { var r = "yes"; }`, "no"},
+		{`if (0) { var r = "then"; } else { var r = "else"; }`, "else"},
+		{`if ("") { var r = "a"; } else if (null) { var r = "b"; } else { var r = "c"; }`, "c"},
+		{`var x = 5; if (x == 1) { var r = "one"; } else if (x == 5) { var r = "five"; } else { var r = "other"; }`, "five"},
+		{`if ("text") var r = "single";`, "single"},
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test.Input)
+		_, env := WithEnvironment(p.Parse())
+		got, err := env.GetString("r")
+		if err != nil {
+			t.Errorf("test[%04d] %v", idx, err)
+			continue
+		}
+		if got != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, got)
+		}
+	}
+}
+
+func TestEvalReturnStatement(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`fn f() { return 10; 20; } var r = f();`, "10"},
+		{`fn f() { 10; } var r = f();`, "10"},
+		{`fn f() { return; } var r = typeof f();`, "undefined"},
+		{"fn f() { return\n5; } var r = typeof f();", "undefined"},
+		{`fn f() {} var r = typeof f();`, "undefined"},
+		{`fn f() { if (false) { 1; } } var r = typeof f();`, "undefined"},
+		{`fn f(x) { if (x > 1) { if (x > 5) { return "big"; } return "medium"; } return "small"; } var r = f(10);`, "big"},
+		{`fn f(x) { if (x > 1) { if (x > 5) { return "big"; } return "medium"; } return "small"; } var r = f(3);`, "medium"},
+		{`fn f(x) { if (x > 1) { if (x > 5) { return "big"; } return "medium"; } return "small"; } var r = f(0);`, "small"},
+		{`fn outer() { fn inner() { return 1; } inner(); return 2; } var r = outer();`, "2"},
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test.Input)
+		_, env := WithEnvironment(p.Parse())
+		got, ok := env.Get("r")
+		if !ok {
+			t.Errorf("test[%04d] expected %q to be set", idx, "r")
+			continue
+		}
+		if got.Inspect() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, got.Inspect())
+		}
+	}
+}
+
+// topLevel parses input and returns the body of its first statement, a
+// function declaration or a while loop, as a program. It builds programs
+// the parser rejects, such as one with a return outside of a function.
+func topLevel(input string) *ast.Program {
+	var body *ast.BlockStatement
+	switch stmt := parser.NewString(input).Parse().Statements[0].(type) {
+	case *ast.FunctionDeclaration:
+		body = stmt.Function.Body
+	case *ast.WhileStatement:
+		body = stmt.Body
+	}
+	return &ast.Program{Statements: body.Statements}
+}
+
+func TestEvalReturnOutsideFunction(t *testing.T) {
+	output, _ := WithEnvironment(topLevel("fn f() {\n  return 5;\n}"))
+	err, ok := output.(*object.Error)
+	if !ok {
+		t.Fatalf("expected error. got %v", output)
+	}
+	if expected := "ERROR: 2:3: illegal return statement outside of function"; err.Inspect() != expected {
+		t.Errorf("expected %q. got %q", expected, err.Inspect())
+	}
+}
+
+func TestEvalLoops(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`var i = 0; var r = 0; while (i < 5) { var r = r + i; var i = i + 1; }`, "10"},
+		{`var r = 0; for (var i = 0; i < 5; ) { var r = r + i; var i = i + 1; }`, "10"},
+		{`var r = 0; for (var x of [1, 2, 3]) { var r = r + x; }`, "6"},
+		{`var r = ""; for (var c of "abc") { var r = c + r; }`, "cba"},
+		{`var r = ""; for (var k in {"b": 1, "a": 2}) { var r = r + k; }`, "ab"},
+		{`var r = 0; for (var k in [5, 6, 7]) { var r = r + k; }`, "3"},
+		{`var r = 0; for (var x of [1, 2, 3, 4]) { if (x == 3) { break; } var r = r + x; }`, "3"},
+		{`var r = 0; for (var x of [1, 2, 3, 4]) { if (x == 3) { continue; } var r = r + x; }`, "7"},
+		{`var r = 0; var i = 0; while (true) { var i = i + 1; if (i > 3) { break; } var r = r + i; }`, "6"},
+		{`var r = 0; for (var x of [1, 2]) { for (var y of [10, 20]) { if (y == 20) { break; } var r = r + x * y; } }`, "30"},
+		{`fn find(xs) { for (var x of xs) { if (x > 2) { return x; } } return null; } var r = find([1, 2, 3, 4]);`, "3"},
+		{`var x = 0; var r = 0; for (x of [4, 5]) { var r = r + x; }`, "9"},
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test.Input)
+		_, env := WithEnvironment(p.Parse())
+		got, ok := env.Get("r")
+		if !ok {
+			t.Errorf("test[%04d] expected %q to be set", idx, "r")
+			continue
+		}
+		if got.Inspect() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, got.Inspect())
+		}
+	}
+}
+
+func TestEvalLoopErrors(t *testing.T) {
+	tests := []*ast.Program{
+		topLevel(`while (true) { break; }`),
+		topLevel(`while (true) { continue; }`),
+		parser.NewString(`for (var x of 5) { }`).Parse(),
+	}
+
+	for idx, test := range tests {
+		output, _ := WithEnvironment(test)
+		if !isError(output) {
+			t.Errorf("test[%04d] expected error. got %v", idx, output)
+		}
+	}
+}
+
+func TestEvalScopes(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`var g = 5; fn f() { return g * 2; } var r = f();`, "10"},
+		{`fn counter(start) { fn next() { return start + 1; } return next; } var r = counter(41)();`, "42"},
+		{`let r = 1; if (true) { let r = 2; }`, "1"},
+		{`var r = 1; if (true) { var r = 2; }`, "2"},
+		{`const r = "c";`, "c"},
+		{`let x = 1; var r = 0; if (true) { let x = 2; var r = x; }`, "2"},
+		{`var r = 0; for (let i = 0; i < 3; ) { let i = 10; var r = r + i; break; }`, "10"},
+		{`var r = 0; for (let x of [1, 2, 3]) { var r = r + x; }`, "6"},
+		{`var r = 0; for (const x of [1, 2, 3]) { let y = x * 2; var r = r + y; }`, "12"},
+		{`var x = "outer"; fn f() { var x = "inner"; return x; } var r = f() + x;`, "innerouter"},
+		{`let r = 1; { let r = 2; }`, "1"},
+		{`var r = 1; { var r = 2; }`, "2"},
+		{`let x = 1; var r; { let x = 2; r = x; }`, "2"},
+		{`let r;`, "undefined"},
+		{`var r = 1; var r;`, "1"},
+		{`var r; for (r in [7, 8]) {}`, "1"},
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test.Input)
+		_, env := WithEnvironment(p.Parse())
+		got, ok := env.Get("r")
+		if !ok {
+			t.Errorf("test[%04d] expected %q to be set", idx, "r")
+			continue
+		}
+		if got.Inspect() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, got.Inspect())
+		}
+	}
+}
+
+func TestEvalScopeErrors(t *testing.T) {
+	tests := []string{
+		`const x = 1; var x = 2;`,
+		`let x = 1; let x = 2;`,
+		`const x = 1; for (x of [1]) { }`,
+		`if (true) { let hidden = 1; } hidden;`,
+		`{ let hidden = 1; } hidden;`,
+		`let x; let x;`,
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test)
+		output, _ := WithEnvironment(p.Parse())
+		if !isError(output) {
+			t.Errorf("test[%04d] expected error. got %v", idx, output)
+		}
+	}
+}
+
+func TestEnvironmentLookupWalksScopes(t *testing.T) {
+	global := object.NewEnvironment()
+	global.Set("name", &object.StringObject{Value: "js"})
+	global.Set("enabled", &object.Boolean{Value: true})
+	inner := object.NewBlockEnvironment(object.NewEnclosedEnvironment(global))
+
+	if got, err := inner.GetString("name"); err != nil || got != "js" {
+		t.Errorf("expected %q. got %q (%v)", "js", got, err)
+	}
+	if got, err := inner.GetBool("enabled"); err != nil || !got {
+		t.Errorf("expected true. got %t (%v)", got, err)
+	}
+}
+
+func TestEvalAssignment(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`var r = 1; r = 5;`, "5"},
+		{`var a = 0; var r = 0; r = a = 7;`, "7"},
+		{`var r = 10; r += 5; r -= 3; r *= 2; r /= 4; r %= 4;`, "2"},
+		{`var r = "a"; r += "b";`, "ab"},
+		{`var r = 0; fn inc() { r = r + 1; } inc(); inc();`, "2"},
+		{`var r = [1, 2, 3]; r[0] = 10; r[1] += 5;`, "[10, 7, 3]"},
+		{`var r = [1]; r[2] = 3;`, "[1, undefined, 3]"},
+		{`var a = []; a[2] = 1; var r = typeof a[0];`, "undefined"},
+		{`var a = []; a[1048575] = 1; var r = a.length;`, "1048576"},
+		{`var a = []; var r = null; try { a[400000000] = 1; } catch (e) { r = e.name + " " + a.length; }`, "RangeError 0"},
+		{`var h = {"k": 1}; h["k"] = 2; h["n"] = 3; var r = h["k"] + h["n"];`, "5"},
+		{`var r = 0; for (var i = 0; i < 5; i++) { r += i; }`, "10"},
+		{`var i = 5; var r = i++ + i;`, "11"},
+		{`var i = 5; var r = ++i + i;`, "12"},
+		{`var i = 5; i--; var r = --i;`, "3"},
+		{`var xs = [1, 2]; var i = 0; xs[i++] += 10; var r = [xs, i];`, "[[11, 2], 1]"},
+		{`let r = 1; if (true) { r = 2; }`, "2"},
+		{"var r = 1\n++r", "2"},
+		{"var c = 1; var r = 5\nc\n--r", "4"},
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test.Input)
+		_, env := WithEnvironment(p.Parse())
+		got, ok := env.Get("r")
+		if !ok {
+			t.Errorf("test[%04d] expected %q to be set", idx, "r")
+			continue
+		}
+		if got.Inspect() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, got.Inspect())
+		}
+	}
+}
+
+func TestEvalAssignmentErrors(t *testing.T) {
+	tests := []string{
+		`const x = 1; x = 2;`,
+		`const x = 1; x++;`,
+		`undeclared = 1;`,
+		`var s = "abc"; s[0] = "x";`,
+		`var xs = []; xs[0 - 1] = 1;`,
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test)
+		output, _ := WithEnvironment(p.Parse())
+		if !isError(output) {
+			t.Errorf("test[%04d] expected error. got %v", idx, output)
+		}
+	}
+}
+
+type testPoint struct {
+	X, Y float64
+}
+
+func (p *testPoint) Type() object.Type { return object.HashType }
+func (p *testPoint) Inspect() string   { return "point" }
+func (p *testPoint) GetProperty(name string) (object.Object, bool) {
+	switch name {
+	case "x":
+		return &object.NumberObject{Value: p.X}, true
+	case "y":
+		return &object.NumberObject{Value: p.Y}, true
+	case "sum":
+		return &object.BuiltinObject{Fn: func(args ...object.Object) object.Object {
+			return &object.NumberObject{Value: p.X + p.Y}
+		}}, true
+	}
+	return nil, false
+}
+
+func TestEvalMemberExpression(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`var o = {name: "js", "version": 1}; var r = o.name;`, "js"},
+		{`var o = {inner: {deep: {value: 42}}}; var r = o.inner.deep.value;`, "42"},
+		{`var o = {}; var r = o.missing;`, "undefined"},
+		{`var o = {}; var r = typeof o.missing;`, "undefined"},
+		{`var o = {}; var r = o["missing"];`, "undefined"},
+		{`var r = typeof [1, 2][5];`, "undefined"},
+		{`var o = {n: 1}; o.n = 5; o.m = 2; var r = o.n + o.m;`, "7"},
+		{`var o = {n: 1}; o.n += 10; o.n++; var r = o.n;`, "12"},
+		{`var o = {n: 3, get: fn get() { return this.n; }}; var r = o.get();`, "3"},
+		{`var o = {a: {n: 4, get: fn get() { return this.n; }}}; var r = o.a.get();`, "4"},
+		{`var o = {n: 5, get: fn get() { return this.n; }}; var r = o["get"]();`, "5"},
+		{`var o = {f: fn f(x) { return x * 2; }}; var r = o.f(21);`, "42"},
+		{`var r = [1, 2, 3].length + "abc".length;`, "6"},
+		{`var o = {if: 1, for: 2}; var r = o.if + o.for;`, "3"},
+		{`var r = point.x + point.y + point.sum();`, "6"},
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test.Input)
+		env := object.NewEnvironment()
+		env.Set("point", &testPoint{X: 1, Y: 2})
+		Eval(p.Parse(), env)
+		got, ok := env.Get("r")
+		if !ok {
+			t.Errorf("test[%04d] expected %q to be set", idx, "r")
+			continue
+		}
+		if got.Inspect() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, got.Inspect())
+		}
+	}
+}
+
+func TestEvalMemberExpressionErrors(t *testing.T) {
+	tests := []string{
+		`var o = null; o.x;`,
+		`var o = {a: null}; o.a.b;`,
+		`var xs = [1]; xs.length = 3;`,
+		`point.x = 5;`,
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test)
+		env := object.NewEnvironment()
+		env.Set("point", &testPoint{X: 1, Y: 2})
+		output := Eval(p.Parse(), env)
+		if !isError(output) {
+			t.Errorf("test[%04d] expected error. got %v", idx, output)
+		}
+	}
+}
+
+func TestEvalNumbers(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`1.5 + 1`, "2.5"},
+		{`10 / 3`, "3.3333333333333335"},
+		{`10 / 4`, "2.5"},
+		{`1 / 0`, "Infinity"},
+		{`0 / 0`, "NaN"},
+		{`1e3`, "1000"},
+		{`2.5e-3`, "0.0025"},
+		{`.5 + .25`, "0.75"},
+		{`0x1F`, "31"},
+		{`0o17`, "15"},
+		{`0b1010`, "10"},
+		{`1_000_000`, "1000000"},
+		{`1e21`, "1e+21"},
+		{`1e-7`, "1e-7"},
+		{`123456789012345680000`, "123456789012345680000"},
+		{`0.1 + 0.2`, "0.30000000000000004"},
+		{`7 % 3`, "1"},
+		{`7.5 % 2`, "1.5"},
+		{`5 % 0`, "NaN"},
+		{`2 ** 10`, "1024"},
+		{`2 ** 3 ** 2`, "512"},
+		{`2 * 3 ** 2`, "18"},
+		{`Infinity - 1`, "Infinity"},
+		{`NaN == NaN`, "false"},
+		{`NaN != NaN`, "true"},
+		{`"3.5" == 3.5`, "true"},
+		{`"0x10" == 16`, "true"},
+		{`"abc" < 1`, "false"},
+		{`var x = 2; x **= 3; x`, "8"},
+		{`[1, 2, 3][1.0]`, "2"},
+		{`[1, 2, 3][1.5]`, "undefined"},
+		{`1 + undefined`, "NaN"},
+		{`1 + null`, "1"},
+		{`true + 1`, "2"},
+		{`"n=" + 1`, "n=1"},
+		{`1 + "2"`, "12"},
+		{`"x" + undefined + null + true`, "xundefinednulltrue"},
+		{`"6" / "2"`, "3"},
+		{`"a" - 1`, "NaN"},
+		{`"3" * true`, "3"},
+		{`[5] * 2`, "10"},
+		{`[1, [2, 3]] + ""`, "1,2,3"},
+		{`[null, undefined, 1] + ""`, ",,1"},
+		{`var a = [1]; a[1] = a; a + ""`, "1,"},
+		{`({}) + 1`, "[object Object]1"},
+		{`({}) - 1`, "NaN"},
+		{`"" + new TypeError("bad")`, "TypeError: bad"},
+		{`"" + new Error()`, "Error"},
+		{`var s = "a"; s += 1; s`, "a1"},
+	}
+
+	for idx, test := range tests {
+		p := parser.NewString(test.Input)
+		output, _ := WithEnvironment(p.Parse())
+		if output == nil {
+			t.Errorf("test[%04d] %s: no result", idx, test.Input)
+			continue
+		}
+		if output.Inspect() != test.Expected {
+			t.Errorf("test[%04d] %s: expected %q. got %q", idx, test.Input, test.Expected, output.Inspect())
+		}
+	}
+}
+
+func TestEvalNumberTruthiness(t *testing.T) {
+	p := parser.NewString(`var r = "truthy"; if (0 / 0) { r = "NaN is truthy"; } if (0.0) { r = "zero is truthy"; }`)
+	_, env := WithEnvironment(p.Parse())
+	if got, _ := env.GetString("r"); got != "truthy" {
+		t.Errorf("expected %q. got %q", "truthy", got)
+	}
+}
+
+func TestEvalIntegerIsNumber(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`len("abc") + 1`, "4"},
+		{`len("abc") === 3`, "true"},
+		{`[10, 20, 30, 40][len("abc")]`, "40"},
+		{`var h = {}; h[3] = "three"; h[len("abc")]`, "three"},
+		{`legacy + 1`, "42"},
+		{`legacy === 41`, "true"},
+		{`var h = {41: "found"}; h[legacy]`, "found"},
+		{`legacyList[0] * 2`, "14"},
+	}
+
+	for idx, test := range tests {
+		env := object.NewEnvironment()
+		env.Set("legacy", &object.Integer{Value: 41})
+		env.Set("legacyList", &object.Array{Elements: []object.Object{&object.Integer{Value: 7}}})
+		output := Eval(parser.NewString(test.Input).Parse(), env)
+		if output == nil {
+			t.Errorf("test[%04d] %s: no result", idx, test.Input)
+			continue
+		}
+		if output.Inspect() != test.Expected {
+			t.Errorf("test[%04d] %s: expected %q. got %q", idx, test.Input, test.Expected, output.Inspect())
+		}
+	}
+
+	integer := &object.Integer{Value: 3}
+	number := &object.NumberObject{Value: 3}
+	if integer.HashKey() != number.HashKey() {
+		t.Errorf("expected Integer and NumberObject to hash alike. got %v and %v", integer.HashKey(), number.HashKey())
+	}
+}
+
+func TestEvalErrorPosition(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"var a = 1;\nvar b = a + missing;", "ERROR: 2:13: ReferenceError: identifier \"missing\" not found"},
+		{"var o = null;\n  o.name;", "ERROR: 2:4: TypeError: cannot read property \"name\" of null"},
+		{
+			"fn inner(x) {\n  return x.missing;\n}\nfn outer() {\n  return inner(null);\n}\nouter();",
+			"ERROR: 2:11: TypeError: cannot read property \"missing\" of null\n    at inner (2:11)\n    at outer (5:10)\n    at 7:1",
+		},
+	}
+
+	for idx, test := range tests {
+		output, _ := WithEnvironment(parser.NewString(test.Input).Parse())
+		err, ok := output.(*object.Error)
+		if !ok {
+			t.Errorf("test[%04d] expected error. got %v", idx, output)
+			continue
+		}
+		if err.Inspect() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, err.Inspect())
+		}
+	}
+}
+
+func TestEvalStringsAndTemplates(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`"a\tb!"`, "a\tb!"},
+		{`len("\u{1F600}")`, "4"},
+		{"var name = \"js\"; `hello ${name}!`", "hello js!"},
+		{"var a = 2; `${a} * ${a} = ${a * a}`", "2 * 2 = 4"},
+		{"`${[1, 2].length} ${null} ${true}`", "2 null true"},
+		{"`${[1, 2]} ${({})}`", "1,2 [object Object]"},
+		{"var a = []; a[0] = a; `${a}`", ""},
+		{"var a = [1]; a[1] = a; `${a}` == \"\" + a", "true"},
+		{"var o = {n: 1}; `${`nested ${o.n + 1}`}`", "nested 2"},
+		{"`line\none`", "line\none"},
+		{"var café = 1; var $π = 2; var _名前 = 3; café + $π + _名前", "6"},
+	}
+
+	for idx, test := range tests {
+		output, _ := WithEnvironment(parser.NewString(test.Input).Parse())
+		if output == nil {
+			t.Errorf("test[%04d] %s: no result", idx, test.Input)
+			continue
+		}
+		if output.Inspect() != test.Expected {
+			t.Errorf("test[%04d] %s: expected %q. got %q", idx, test.Input, test.Expected, output.Inspect())
+		}
+	}
+}
+
+func TestEvalLogicalOperators(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`-5 + 2`, "-3"},
+		{`- -5`, "5"},
+		{`+"42" + 1`, "43"},
+		{`+"x"`, "NaN"},
+		{`!true`, "false"},
+		{`!0`, "true"},
+		{`!!"text"`, "true"},
+		{`1 && "yes"`, "yes"},
+		{`0 && "yes"`, "0"},
+		{`"" || "default"`, "default"},
+		{`"value" || "default"`, "value"},
+		{`null ?? "default"`, "default"},
+		{`undefined ?? "default"`, "default"},
+		{`0 ?? "default"`, "0"},
+		{`5 > 3 ? "big" : "small"`, "big"},
+		{`var n = 0; n ? "a" : n === 0 ? "zero" : "b"`, "zero"},
+		{`typeof 1`, "number"},
+		{`typeof "s"`, "string"},
+		{`typeof true`, "boolean"},
+		{`typeof null`, "object"},
+		{`typeof {}`, "object"},
+		{`typeof undefined`, "undefined"},
+		{`typeof notDeclared`, "undefined"},
+		{`typeof len`, "function"},
+		{`null == undefined`, "true"},
+		{`null === undefined`, "false"},
+		{`undefined == 0`, "false"},
+		{`var o = {a: {b: 2}}; o?.a?.b`, "2"},
+		{`var o = null; o?.a.b.c`, "undefined"},
+		{`var o = {}; o.a?.b`, "undefined"},
+		{`var o = {}; o.a?.b.c.d`, "undefined"},
+		{`var o = {f: fn f() { return 7; }}; o.f?.()`, "7"},
+		{`var o = {}; o.f?.()`, "undefined"},
+		{`var o = {}; o.list?.[0]`, "undefined"},
+		{`var o = null; o?.a ?? "fallback"`, "fallback"},
+	}
+
+	for idx, test := range tests {
+		output, _ := WithEnvironment(parser.NewString(test.Input).Parse())
+		if output == nil {
+			t.Errorf("test[%04d] %s: no result", idx, test.Input)
+			continue
+		}
+		if output.Inspect() != test.Expected {
+			t.Errorf("test[%04d] %s: expected %q. got %q", idx, test.Input, test.Expected, output.Inspect())
+		}
+	}
+}
+
+func TestEvalShortCircuit(t *testing.T) {
+	tests := []string{
+		`false && touch()`,
+		`true || touch()`,
+		`1 ?? touch()`,
+		`true ? 1 : touch()`,
+		`false ? touch() : 1`,
+		`var o = null; o?.a[touch()]`,
+		`var o = null; o?.f(touch())`,
+	}
+
+	for idx, input := range tests {
+		env := object.NewEnvironment()
+		calls := 0
+		env.Set("touch", &object.BuiltinObject{Fn: func(args ...object.Object) object.Object {
+			calls++
+			return &object.NullObject{}
+		}})
+		Eval(parser.NewString(input).Parse(), env)
+		if calls != 0 {
+			t.Errorf("test[%04d] %s: expected the right operand to be skipped", idx, input)
+		}
+	}
+}
+
+func TestEvalOptionalChainErrors(t *testing.T) {
+	output, _ := WithEnvironment(parser.NewString(`var o = {}; o.a?.b; o.a.b`).Parse())
+	if !isError(output) || !strings.Contains(output.Inspect(), `cannot read property "b" of undefined`) {
+		t.Errorf("expected only the plain member access to fail. got %v", output)
+	}
+}
+
+func TestEvalFunctions(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`var f = fn(a) { return a * 2; }; f(21)`, "42"},
+		{`var f = function(a, b) { return a + b; }; f(1, 2)`, "3"},
+		{`function add(a, b) { return a + b; } add(2, 3)`, "5"},
+		{`var add = (a, b) => a + b; add(4, 5)`, "9"},
+		{`var double = x => x * 2; double(8)`, "16"},
+		{`var f = () => { var x = 1; return x + 1; }; f()`, "2"},
+		{`var adder = a => b => a + b; adder(1)(2)`, "3"},
+		{`(x => x + 1)(1)`, "2"},
+		{`fn counter() { var n = 0; return () => { n += 1; return n; }; } var c = counter(); c(); c(); c()`, "3"},
+		{`fn counter() { var n = 0; return () => { n += 1; return n; }; } var a = counter(); var b = counter(); a(); a(); b()`, "1"},
+		{`var fact = fn f(n) { return n < 2 ? 1 : n * f(n - 1); }; fact(5)`, "120"},
+		{`var g = fn f() { return 1; }; typeof f`, "undefined"},
+		{`var o = {n: 2, get: fn() { return this.n; }}; o.get()`, "2"},
+		{`var o = {n: 3, get: fn() { var inner = () => this.n; return inner(); }}; o.get()`, "3"},
+		{`var o = {n: 4, get: fn() { var inner = fn() { return this; }; return inner(); }}; o.get()`, "null"},
+		{`var fs = []; for (let i = 0; i < 3; i++) { fs[i] = () => i; } fs[0]() + fs[1]() + fs[2]()`, "3"},
+	}
+
+	for idx, test := range tests {
+		output, _ := WithEnvironment(parser.NewString(test.Input).Parse())
+		if output == nil {
+			t.Errorf("test[%04d] %s: no result", idx, test.Input)
+			continue
+		}
+		if output.Inspect() != test.Expected {
+			t.Errorf("test[%04d] %s: expected %q. got %q", idx, test.Input, test.Expected, output.Inspect())
+		}
+	}
+}
+
+func TestEvalParametersAndSpread(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`fn f(a, b) { return b; } f(1)`, "undefined"},
+		{`fn f(a, b) { return a; } f(1, 2, 3)`, "1"},
+		{`fn f(a, b = 2) { return a + b; } f(1)`, "3"},
+		{`fn f(a, b = 2) { return a + b; } f(1, 5)`, "6"},
+		{`fn f(a, b = 2) { return a + b; } f(1, undefined)`, "3"},
+		{`fn f(a, b = a * 10) { return b; } f(4)`, "40"},
+		{`fn f(a, ...rest) { return rest; } f(1, 2, 3)`, "[2, 3]"},
+		{`fn f(a, ...rest) { return rest.length; } f()`, "0"},
+		{`var f = (...xs) => xs.length; f(1, 2, 3)`, "3"},
+		{`fn f() { return arguments.length; } f(1, 2, 3)`, "3"},
+		{`fn f(a) { return arguments[1]; } f(1, 2)`, "2"},
+		{`fn add(a, b, c) { return a + b + c; } var xs = [1, 2, 3]; add(...xs)`, "6"},
+		{`fn add(a, b, c) { return a + b + c; } add(1, ...[2, 3])`, "6"},
+		{`var xs = [2, 3]; [1, ...xs, 4]`, "[1, 2, 3, 4]"},
+		{`[..."ab"]`, "[a, b]"},
+		{`var a = {x: 1, y: 2}; var b = {...a, y: 3}; b.x + b.y`, "4"},
+		{`var a = {y: 2}; var b = {y: 1, ...a}; b.y`, "2"},
+		{`var b = {...null, x: 1}; b.x`, "1"},
+	}
+
+	for idx, test := range tests {
+		output, _ := WithEnvironment(parser.NewString(test.Input).Parse())
+		if output == nil {
+			t.Errorf("test[%04d] %s: no result", idx, test.Input)
+			continue
+		}
+		if output.Inspect() != test.Expected {
+			t.Errorf("test[%04d] %s: expected %q. got %q", idx, test.Input, test.Expected, output.Inspect())
+		}
+	}
+
+	output, _ := WithEnvironment(parser.NewString(`fn f(a) { return a; } f(...1)`).Parse())
+	if output == nil || output.Type() != object.ErrorType {
+		t.Errorf("expected an error spreading a number. got %v", output)
+	}
+}
+
+func TestRunRecoversPanics(t *testing.T) {
+	environment := object.NewEnvironment()
+	environment.Set("boom", &object.BuiltinObject{Fn: func(args ...object.Object) object.Object {
+		panic("boom")
+	}})
+
+	result, err := Run(parser.NewString("var x = 1;\nx + boom();").Parse(), environment)
+	if result != nil {
+		t.Errorf("expected no result. got %v", result)
+	}
+	perr, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("expected a *PanicError. got %T %v", err, err)
+	}
+	if perr.Value != "boom" {
+		t.Errorf("expected panic value %q. got %v", "boom", perr.Value)
+	}
+	if perr.Position.Line != 2 || perr.Position.Column != 9 {
+		t.Errorf("expected the panic at 2:9. got %s", perr.Position)
+	}
+	if len(perr.Stack) == 0 {
+		t.Errorf("expected a Go stack")
+	}
+	if err.Error() != "2:9: panic: boom" {
+		t.Errorf("expected %q. got %q", "2:9: panic: boom", err.Error())
+	}
+}
+
+func TestRunNoPanics(t *testing.T) {
+	inputs := []string{
+		`1 < null`, `-null`, `[1][null]`, `"a" - "b"`, `"a" * "b"`, `var x = 1; x()`, `null.x`,
+		`fn f() {} f() + 1`, `len()`, `println()`, `fn f(a, b) { return a + b; } f()`,
+		`[...null]`, `for (var k in null) {}`, `var a; a.b`, `fn f(a = b) {} f()`,
+	}
+	for _, input := range inputs {
+		if _, err := Run(parser.NewString(input).Parse(), object.NewEnvironment()); err != nil {
+			if _, ok := err.(*object.Error); !ok {
+				t.Errorf("%s: expected a script error. got %T %v", input, err, err)
+			}
+		}
+	}
+}
+
+func TestEvalExceptions(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`var r = null; try { throw 1; } catch (e) { r = e; } r`, "1"},
+		{`var r = null; try { throw new Error("boom"); } catch (e) { r = e.message; } r`, "boom"},
+		{`var r = null; try { throw TypeError("bad"); } catch (e) { r = e.name; } r`, "TypeError"},
+		{`var r = null; try { missing; } catch (e) { r = e.name + ": " + e.message; } r`, `ReferenceError: identifier "missing" not found`},
+		{`var r = null; try { null.x; } catch (e) { r = e.name; } r`, "TypeError"},
+		{`var r = null; try { undeclared = 1; } catch (e) { r = e.name + ": " + e.message; } r`, `ReferenceError: "undeclared" is not defined`},
+		{`var r = null; try { undeclared++; } catch (e) { r = e.name; } r`, "ReferenceError"},
+		{`var r = null; try { for (undeclared in [1]) {} } catch (e) { r = e.name; } r`, "ReferenceError"},
+		{`const c = 1; var r = null; try { c = 2; } catch (e) { r = e.name + ": " + e.message; } r`, `TypeError: assignment to constant variable "c"`},
+		{`const c = 1; var r = null; try { c += 2; } catch (e) { r = e.name; } r`, "TypeError"},
+		{`var r = 0; try { r = 1; } catch (e) { r = 2; } r`, "1"},
+		{`var r = 0; try { throw 1; } catch { r = 2; } r`, "2"},
+		{`var r = []; try { r[0] = 1; } finally { r[1] = 2; } r`, "[1, 2]"},
+		{`var r = 0; try { throw 1; } catch (e) { r += 1; } finally { r += 10; } r`, "11"},
+		{`fn f() { try { return 1; } finally { return 2; } } f()`, "2"},
+		{`fn f() { try { throw 1; } finally { return 2; } } f()`, "2"},
+		{`fn f() { try { return 1; } catch (e) { return 2; } } f()`, "1"},
+		{`var r = 0; for (var i = 0; i < 3; i++) { try { if (i == 1) { break; } } finally { r += 1; } } r`, "2"},
+		{`fn f() { throw new RangeError("deep"); } fn g() { return f(); } var r = null; try { g(); } catch (e) { r = e.message; } r`, "deep"},
+		{`var r = null; try { try { throw 1; } finally { r = 5; } } catch (e) { r += e; } r`, "6"},
+		{`var r = null; try { try { throw 1; } catch (e) { throw e + 1; } } catch (e) { r = e; } r`, "2"},
+		{`var e = new Error("x"); e.stack`, "Error: x"},
+		{`var e = Error(); e.message`, ""},
+		{`fn Point(x) { this.x = x; } var p = new Point(3); p.x`, "3"},
+		{`fn Make() { return {y: 4}; } var p = new Make(); p.y`, "4"},
+	}
+
+	for idx, test := range tests {
+		output, _ := WithEnvironment(parser.NewString(test.Input).Parse())
+		if output == nil {
+			t.Errorf("test[%04d] %s: no result", idx, test.Input)
+			continue
+		}
+		if output.Inspect() != test.Expected {
+			t.Errorf("test[%04d] %s: expected %q. got %q", idx, test.Input, test.Expected, output.Inspect())
+		}
+	}
+}
+
+func TestEvalUncaughtException(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"throw 1;", "ERROR: 1:1: 1"},
+		{"fn f() {\n  throw new TypeError(\"bad\");\n}\nf();", "ERROR: 2:3: TypeError: bad\n    at f (2:3)\n    at 4:1"},
+		{"var x = () => 1;\nnew x();", "ERROR: 2:1: TypeError: x is not a constructor"},
+	}
+
+	for idx, test := range tests {
+		output, _ := WithEnvironment(parser.NewString(test.Input).Parse())
+		if output == nil || output.Inspect() != test.Expected {
+			t.Errorf("test[%04d] %s: expected %q. got %v", idx, test.Input, test.Expected, output)
+		}
+	}
+
+	output, _ := WithEnvironment(parser.NewString("fn f() {\n  null.x;\n}\nvar s = null;\ntry { f(); } catch (e) { s = e.stack; } s").Parse())
+	expected := "TypeError: cannot read property \"x\" of null\n    at f (2:7)\n    at 5:7"
+	if output == nil || output.Inspect() != expected {
+		t.Errorf("expected stack %q. got %q", expected, output.Inspect())
+	}
+}
+
+func TestEvalHaltsOnUncaughtError(t *testing.T) {
+	environment := object.NewEnvironment()
+	result, err := Run(parser.NewString("var a = 1;\nmissing();\nvar b = 2;").Parse(), environment)
+	if result != nil {
+		t.Errorf("expected no result. got %v", result)
+	}
+	if err == nil || err.Error() != `2:1: ReferenceError: identifier "missing" not found` {
+		t.Errorf("expected the uncaught error. got %v", err)
+	}
+	if _, ok := environment.Get("b"); ok {
+		t.Errorf("expected evaluation to stop before b was declared")
+	}
+
+	result, err = Run(parser.NewString("var a = 1; a + 1").Parse(), object.NewEnvironment())
+	if err != nil || result.Inspect() != "2" {
+		t.Errorf("expected 2. got %v, %v", result, err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	environment := object.NewEnvironment()
+	program := parser.NewString(`
+fn add(a, b) { return a + b; }
+fn fail() { null.x; }
+var counter = {n: 0, inc: fn(by) { this.n += by; return this.n; }};
+`).Parse()
+	if _, err := Run(program, environment); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	add, _ := environment.Get("add")
+	result, err := Call(add, nil, &object.NumberObject{Value: 1}, &object.NumberObject{Value: 2})
+	if err != nil || result.Inspect() != "3" {
+		t.Errorf("expected 3. got %v, %v", result, err)
+	}
+
+	counter, _ := environment.Get("counter")
+	inc, _ := counter.(*object.Hash).GetProperty("inc")
+	Call(inc, counter, &object.NumberObject{Value: 5})
+	if result, err := Call(inc, counter, &object.NumberObject{Value: 5}); err != nil || result.Inspect() != "10" {
+		t.Errorf("expected this to be bound. got %v, %v", result, err)
+	}
+
+	fail, _ := environment.Get("fail")
+	if _, err := Call(fail, nil); err == nil || err.Error() != `3:17: TypeError: cannot read property "x" of null` {
+		t.Errorf("expected the script error. got %v", err)
+	}
+
+	if _, err := Call(&object.NumberObject{Value: 1}, nil); err == nil {
+		t.Errorf("expected an error calling a number")
+	}
+
+	panics := &object.BuiltinObject{Fn: func(args ...object.Object) object.Object { panic("boom") }}
+	if _, err := Call(panics, nil); err == nil {
+		t.Errorf("expected the panic to be recovered")
+	} else if _, ok := err.(*PanicError); !ok {
+		t.Errorf("expected a *PanicError. got %T", err)
+	}
+}
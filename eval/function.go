@@ -0,0 +1,33 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+)
+
+// newFunction creates the closure for fn, which captures environment.
+func newFunction(fn *ast.FunctionLiteral, environment *object.Environment) *object.Function {
+	return &object.Function{
+		Token:       fn.Token,
+		Name:        fn.Name,
+		Parameters:  fn.Parameters,
+		Defaults:    fn.Defaults,
+		Rest:        fn.Rest,
+		Body:        fn.Body,
+		Environment: environment,
+		Arrow:       fn.Arrow,
+	}
+}
+
+// evalFunctionLiteral evaluates a function expression. The name of a named
+// function expression is only visible inside the function itself.
+func evalFunctionLiteral(fn *ast.FunctionLiteral, environment *object.Environment) object.Object {
+	if fn.Name == "" {
+		return newFunction(fn, environment)
+	}
+
+	scope := object.NewEnclosedEnvironment(environment)
+	function := newFunction(fn, scope)
+	scope.Set(fn.Name, function)
+	return function
+}
@@ -0,0 +1,21 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+)
+
+func evalIfStatement(node *ast.IfStatement, environment *object.Environment) object.Object {
+	condition := Eval(node.Condition, environment)
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return Eval(node.Consequence, environment)
+	}
+	if node.Alternative != nil {
+		return Eval(node.Alternative, environment)
+	}
+	return nil
+}
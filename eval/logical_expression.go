@@ -0,0 +1,51 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+)
+
+// evalLogicalExpression evaluates &&, || and ??. The result is one of the
+// operands, and the right one is only evaluated when the left one does not
+// decide the result.
+func evalLogicalExpression(n *ast.LogicalExpression, env *object.Environment) object.Object {
+	left := Eval(n.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	switch n.Operator {
+	case "&&":
+		if !isTruthy(left) {
+			return left
+		}
+	case "||":
+		if isTruthy(left) {
+			return left
+		}
+	case "??":
+		if !isNullish(left) {
+			return left
+		}
+	}
+	return Eval(n.Right, env)
+}
+
+func evalConditionalExpression(n *ast.ConditionalExpression, env *object.Environment) object.Object {
+	condition := Eval(n.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+	if isTruthy(condition) {
+		return Eval(n.Consequence, env)
+	}
+	return Eval(n.Alternative, env)
+}
+
+func isNullish(obj object.Object) bool {
+	switch obj.(type) {
+	case nil, *object.NullObject, *object.UndefinedObject:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+	"sort"
+)
+
+// evalLoopBody runs one iteration of a loop body. It reports whether the loop
+// has to stop and, if so, what the loop statement evaluates to: nothing for
+// a break, the signal itself for a return value or an error.
+func evalLoopBody(body *ast.BlockStatement, environment *object.Environment) (object.Object, bool) {
+	result := Eval(body, environment)
+	if result == nil {
+		return nil, false
+	}
+
+	switch result.Type() {
+	case object.BreakType:
+		return nil, true
+	case object.ReturnValueType, object.ErrorType:
+		return result, true
+	}
+	return nil, false
+}
+
+func evalWhileStatement(node *ast.WhileStatement, environment *object.Environment) object.Object {
+	for {
+		condition := Eval(node.Condition, environment)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		if result, stop := evalLoopBody(node.Body, environment); stop {
+			return result
+		}
+	}
+}
+
+func evalForStatement(node *ast.ForStatement, environment *object.Environment) object.Object {
+	environment = object.NewBlockEnvironment(environment)
+	perIteration := isLexicalDeclaration(node.Init)
+
+	if node.Init != nil {
+		init := Eval(node.Init, environment)
+		if isError(init) {
+			return init
+		}
+	}
+
+	for {
+		if node.Condition != nil {
+			condition := Eval(node.Condition, environment)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return nil
+			}
+		}
+
+		if result, stop := evalLoopBody(node.Body, environment); stop {
+			return result
+		}
+
+		// Closures created by the body keep the bindings of their own
+		// iteration, so let variables move to a fresh scope before the update.
+		if perIteration {
+			environment = environment.Copy()
+		}
+
+		if node.Update != nil {
+			update := Eval(node.Update, environment)
+			if isError(update) {
+				return update
+			}
+		}
+	}
+}
+
+func evalForInStatement(node *ast.ForInStatement, environment *object.Environment) object.Object {
+	iterable := Eval(node.Iterable, environment)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var next func(i int) (object.Object, bool)
+	if node.Of {
+		next = valuesOf(iterable)
+		if next == nil {
+			return NotIterable(iterable)
+		}
+	} else {
+		next = keysOf(iterable)
+	}
+
+	for i := 0; ; i++ {
+		value, ok := next(i)
+		if !ok {
+			return nil
+		}
+
+		scope := object.NewBlockEnvironment(environment)
+		if node.Declaration != nil {
+			if err := declare(node.Declaration.Type, node.Name.Value, value, scope); err != nil {
+				return err
+			}
+		} else if err := environment.Assign(node.Name.Value, value); err != nil {
+			return err
+		}
+
+		if result, stop := evalLoopBody(node.Body, scope); stop {
+			return result
+		}
+	}
+}
+
+// valuesOf returns an iterator over the values a for...of loop visits, or nil
+// if obj is not iterable. Arrays are read live so the body may grow them.
+func valuesOf(obj object.Object) func(i int) (object.Object, bool) {
+	switch v := obj.(type) {
+	case *object.Array:
+		return func(i int) (object.Object, bool) {
+			if i >= len(v.Elements) {
+				return nil, false
+			}
+			return v.Elements[i], true
+		}
+	case *object.StringObject:
+		chars := []rune(v.Value)
+		return func(i int) (object.Object, bool) {
+			if i >= len(chars) {
+				return nil, false
+			}
+			return &object.StringObject{Value: string(chars[i])}, true
+		}
+	}
+	return nil
+}
+
+// keysOf returns an iterator over the keys a for...in loop visits. Hash keys
+// are visited in sorted order so iteration is deterministic.
+func keysOf(obj object.Object) func(i int) (object.Object, bool) {
+	var length int
+	switch v := obj.(type) {
+	case *object.Hash:
+		keys := sortedHashKeys(v)
+		return func(i int) (object.Object, bool) {
+			if i >= len(keys) {
+				return nil, false
+			}
+			return keys[i], true
+		}
+	case *object.Array:
+		length = len(v.Elements)
+	case *object.StringObject:
+		length = len([]rune(v.Value))
+	}
+
+	return func(i int) (object.Object, bool) {
+		if i >= length {
+			return nil, false
+		}
+		return &object.NumberObject{Value: float64(i)}, true
+	}
+}
+
+func sortedHashKeys(hash *object.Hash) []object.Object {
+	keys := make([]object.Object, 0, len(hash.Pairs))
+	for _, pair := range hash.Pairs {
+		keys = append(keys, pair.Key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Inspect() < keys[j].Inspect()
+	})
+	return keys
+}
+
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "undefined"
+	}
+	return obj.Inspect()
+}
@@ -0,0 +1,82 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+)
+
+func evalMemberExpression(node *ast.MemberExpression, environment *object.Environment) object.Object {
+	obj := Eval(node.Object, environment)
+	if isError(obj) {
+		return obj
+	}
+	if isChainEnd(obj, node.Optional) {
+		return &chainEnd{}
+	}
+	return getProperty(obj, node.Property.Value)
+}
+
+// getProperty reads obj.name. Missing properties read as undefined; reading
+// a property of null or undefined itself is an error.
+func getProperty(obj object.Object, name string) object.Object {
+	switch v := obj.(type) {
+	case nil, *object.NullObject, *object.UndefinedObject:
+		return newTypeError("cannot read property %q of %s", name, inspect(obj))
+	case object.PropertyGetter:
+		if value, ok := v.GetProperty(name); ok {
+			return object.Normalize(value)
+		}
+	}
+	return &object.UndefinedObject{}
+}
+
+func propertyReference(obj object.Object, name string) (*reference, object.Object) {
+	setter, ok := obj.(object.PropertySetter)
+	if !ok {
+		return nil, newTypeError("cannot set property %q of %s", name, inspect(obj))
+	}
+
+	return &reference{
+		get: func() object.Object {
+			return getProperty(obj, name)
+		},
+		set: func(value object.Object) object.Object {
+			if err := setter.SetProperty(name, value); err != nil {
+				return newError("%s", err)
+			}
+			return value
+		},
+	}, nil
+}
+
+// evalCallee evaluates the function part of a call. Calling a member,
+// obj.method() or obj["method"](), binds this to obj.
+func evalCallee(node ast.Expression, environment *object.Environment) (fn object.Object, this object.Object) {
+	switch node := node.(type) {
+	case *ast.MemberExpression:
+		this = Eval(node.Object, environment)
+		if isError(this) {
+			return this, nil
+		}
+		if isChainEnd(this, node.Optional) {
+			return &chainEnd{}, nil
+		}
+		return getProperty(this, node.Property.Value), this
+
+	case *ast.IndexExpression:
+		this = Eval(node.Left, environment)
+		if isError(this) {
+			return this, nil
+		}
+		if isChainEnd(this, node.Optional) {
+			return &chainEnd{}, nil
+		}
+		index := Eval(node.Index, environment)
+		if isError(index) {
+			return index, nil
+		}
+		return evalIndex(this, index), this
+	}
+
+	return Eval(node, environment), nil
+}
@@ -0,0 +1,257 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/object"
+	"github.com/bundgaard/js/token"
+	"strings"
+)
+
+// The operations below are the building blocks of Eval, exported for the
+// virtual machine in package vm so that compiled scripts behave exactly like
+// evaluated ones. Errors are returned as *object.Error values, as by Eval.
+
+// Lookup resolves name the way an identifier does: in env, then among the
+// builtins and global constants.
+func Lookup(name string, env *object.Environment) (object.Object, bool) {
+	if val, ok := env.Get(name); ok {
+		return val, true
+	}
+	if builtin, ok := builtins[name]; ok {
+		return builtin, true
+	}
+	return globalConstant(name)
+}
+
+// Identifier resolves name or returns the ReferenceError of an undeclared
+// name.
+func Identifier(name string, env *object.Environment) object.Object {
+	if val, ok := Lookup(name, env); ok {
+		return val
+	}
+	return newReferenceError("identifier %q not found", name)
+}
+
+// Typeof is typeof name, which is "undefined" rather than an error for an
+// undeclared name.
+func Typeof(name string, env *object.Environment) object.Object {
+	if val, ok := Lookup(name, env); ok {
+		return prefixOperation("typeof", val)
+	}
+	return &object.StringObject{Value: "undefined"}
+}
+
+// Infix applies a binary operator.
+func Infix(operator string, left, right object.Object, env *object.Environment) object.Object {
+	return evalInfixExpression(operator, left, right, env)
+}
+
+// Prefix applies one of the prefix operators !, -, + and typeof.
+func Prefix(operator string, right object.Object) object.Object {
+	return prefixOperation(operator, right)
+}
+
+func Truthy(obj object.Object) bool  { return isTruthy(obj) }
+func Nullish(obj object.Object) bool { return isNullish(obj) }
+
+// Inspect formats obj the way println does.
+func Inspect(obj object.Object) string { return inspect(obj) }
+
+// Declare binds name as a declaration with the keyword kind would. A nil
+// value stands for a declaration without one.
+func Declare(kind token.Type, name string, value object.Object, env *object.Environment) object.Object {
+	return declare(kind, name, value, env)
+}
+
+// Assign assigns value to the existing variable name and returns value.
+func Assign(name string, value object.Object, env *object.Environment) object.Object {
+	if err := env.Assign(name, value); err != nil {
+		return err
+	}
+	return value
+}
+
+// Index reads left[index].
+func Index(left, index object.Object) object.Object {
+	return evalIndex(left, index)
+}
+
+// GetProperty reads obj.name.
+func GetProperty(obj object.Object, name string) object.Object {
+	return getProperty(obj, name)
+}
+
+// SetIndex assigns left[index] = value and returns value.
+func SetIndex(left, index, value object.Object, env *object.Environment) object.Object {
+	ref, err := indexReference(left, index, env)
+	if err != nil {
+		return err
+	}
+	return ref.set(value)
+}
+
+// SetProperty assigns obj.name = value and returns value.
+func SetProperty(obj object.Object, name string, value object.Object) object.Object {
+	ref, err := propertyReference(obj, name)
+	if err != nil {
+		return err
+	}
+	return ref.set(value)
+}
+
+// UpdateName applies ++ or -- to the variable name and returns the value of
+// the expression.
+func UpdateName(operator string, prefix bool, name string, env *object.Environment) object.Object {
+	current := Identifier(name, env)
+	if isError(current) {
+		return current
+	}
+	updated, result := increment(operator, prefix, current)
+	if isError(updated) {
+		return updated
+	}
+	if err := env.Assign(name, updated); err != nil {
+		return err
+	}
+	return result
+}
+
+// UpdateIndex applies ++ or -- to left[index].
+func UpdateIndex(operator string, prefix bool, left, index object.Object, env *object.Environment) object.Object {
+	ref, err := indexReference(left, index, env)
+	if err != nil {
+		return err
+	}
+	return update(operator, prefix, ref)
+}
+
+// UpdateProperty applies ++ or -- to obj.name.
+func UpdateProperty(operator string, prefix bool, obj object.Object, name string) object.Object {
+	ref, err := propertyReference(obj, name)
+	if err != nil {
+		return err
+	}
+	return update(operator, prefix, ref)
+}
+
+// IndexReference reads left[index] as the target of a compound assignment,
+// which fails if left[index] cannot be assigned.
+func IndexReference(left, index object.Object, env *object.Environment) object.Object {
+	ref, err := indexReference(left, index, env)
+	if err != nil {
+		return err
+	}
+	return ref.get()
+}
+
+// PropertyReference reads obj.name as the target of a compound assignment.
+func PropertyReference(obj object.Object, name string) object.Object {
+	ref, err := propertyReference(obj, name)
+	if err != nil {
+		return err
+	}
+	return ref.get()
+}
+
+// NewArray creates an array literal's value.
+func NewArray(elements []object.Object, env *object.Environment) object.Object {
+	return newArray(elements, env)
+}
+
+func newArray(elements []object.Object, env *object.Environment) object.Object {
+	if err := allocate(env, len(elements)); err != nil {
+		return err
+	}
+	return &object.Array{Elements: elements}
+}
+
+// NewHash creates an object literal's value from its keys and values in
+// source order. A nil key spreads the properties of its value.
+func NewHash(keys, values []object.Object, env *object.Environment) object.Object {
+	pairs := make(map[object.HashKey]object.HashPair, len(keys))
+	for i, key := range keys {
+		if key == nil {
+			spreadProperties(values[i], pairs)
+			continue
+		}
+		hkey, ok := key.(object.Hashable)
+		if !ok {
+			return newTypeError("unushable hash key: %q", key.Type())
+		}
+		pairs[hkey.HashKey()] = object.HashPair{Key: key, Value: values[i]}
+	}
+	if err := allocate(env, len(pairs)); err != nil {
+		return err
+	}
+	return &object.Hash{Pairs: pairs}
+}
+
+// Template joins the parts of a template literal, the strings and the values
+// of the substitutions converted to strings as + converts them.
+func Template(parts []object.Object, env *object.Environment) object.Object {
+	var out strings.Builder
+	for _, part := range parts {
+		out.WriteString(toString(part))
+	}
+	if err := allocate(env, out.Len()); err != nil {
+		return err
+	}
+	return &object.StringObject{Value: out.String()}
+}
+
+// Values returns an iterator over the values of obj that for...of and
+// spreading visit, or nil if obj is not iterable.
+func Values(obj object.Object) func(i int) (object.Object, bool) {
+	return valuesOf(obj)
+}
+
+// Keys returns an iterator over the keys for...in visits.
+func Keys(obj object.Object) func(i int) (object.Object, bool) {
+	return keysOf(obj)
+}
+
+// NotIterable is the error for spreading or iterating with for...of over obj.
+func NotIterable(obj object.Object) *object.Error {
+	return newTypeError("%s is not iterable", inspect(obj))
+}
+
+// Throw returns the error a throw statement raises for value.
+func Throw(value object.Object) *object.Error {
+	return throwValue(value)
+}
+
+// Catch returns the value a catch clause binds for err.
+func Catch(err *object.Error) object.Object {
+	return catchValue(err)
+}
+
+// Apply calls a builtin or a function created by Eval from a script running
+// in env, whose budget the call is held to. Errors raised in a function are
+// given a stack frame for a call from position call.
+func Apply(fn, this object.Object, args []object.Object, env *object.Environment, call token.Position) object.Object {
+	result := applyFunction(fn, this, args, env.Budget())
+	if err, ok := result.(*object.Error); ok {
+		switch fn.(type) {
+		case *object.Function, *object.Closure:
+			unwind(err, fn, call)
+		}
+	}
+	return result
+}
+
+// unwind records that err propagated out of a call to fn from position
+// call. An error raised by the call itself, such as exceeding the maximum
+// call depth, happened at the call rather than inside fn.
+func unwind(err *object.Error, fn object.Object, call token.Position) {
+	if !err.Position.IsValid() {
+		err.Position = call
+		return
+	}
+	err.Unwind(functionName(fn), call)
+}
+
+// Construct is new callee(args) for a builtin or a function created by
+// Eval, called from a script running in env. name is how the callee is
+// written in the source.
+func Construct(callee object.Object, name string, args []object.Object, env *object.Environment, call token.Position) object.Object {
+	return construct(callee, name, args, env.Budget(), call)
+}
@@ -0,0 +1,32 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+)
+
+// chainEnd is what the links of an optional chain evaluate to after a ?.
+// link found null or undefined. It is passed up the chain in place of a
+// value and turned into undefined by the enclosing ast.OptionalChain.
+type chainEnd struct{}
+
+func (c *chainEnd) Type() object.Type { return object.UndefinedType }
+func (c *chainEnd) Inspect() string   { return "undefined" }
+
+// isChainEnd reports whether the chain link that evaluated to obj ends an
+// optional chain: obj is already a chainEnd, or obj is nullish and the
+// link is optional.
+func isChainEnd(obj object.Object, optional bool) bool {
+	if _, ok := obj.(*chainEnd); ok {
+		return true
+	}
+	return optional && isNullish(obj)
+}
+
+func evalOptionalChain(n *ast.OptionalChain, env *object.Environment) object.Object {
+	result := Eval(n.Expression, env)
+	if _, ok := result.(*chainEnd); ok {
+		return &object.UndefinedObject{}
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+	"math"
+)
+
+func evalPrefixExpression(n *ast.PrefixExpression, env *object.Environment) object.Object {
+	if n.Operator == "typeof" {
+		// typeof is the one operator that accepts an undeclared name.
+		if ident, ok := n.Right.(*ast.Identifier); ok && !isDeclared(ident.Value, env) {
+			return &object.StringObject{Value: "undefined"}
+		}
+	}
+
+	right := Eval(n.Right, env)
+	if isError(right) {
+		return right
+	}
+	return prefixOperation(n.Operator, right)
+}
+
+func prefixOperation(operator string, right object.Object) object.Object {
+	switch operator {
+	case "!":
+		return nativeBoolToBooleanObject(!isTruthy(right))
+	case "-":
+		return &object.NumberObject{Value: -toNumberOrNaN(right)}
+	case "+":
+		return &object.NumberObject{Value: toNumberOrNaN(right)}
+	case "typeof":
+		return &object.StringObject{Value: typeOf(right)}
+	}
+	return newTypeError("unknown operator %s", operator)
+}
+
+// toNumberOrNaN converts obj to a number; objects convert to NaN.
+func toNumberOrNaN(obj object.Object) float64 {
+	if n, ok := toNumber(obj); ok {
+		return n
+	}
+	return math.NaN()
+}
+
+func typeOf(obj object.Object) string {
+	switch obj.Type() {
+	case object.UndefinedType:
+		return "undefined"
+	case object.NumberType:
+		return "number"
+	case object.StringType:
+		return "string"
+	case object.BooleanType:
+		return "boolean"
+	case object.FunctionType, object.BuiltinType:
+		return "function"
+	}
+	return "object"
+}
+
+func isDeclared(name string, env *object.Environment) bool {
+	_, ok := Lookup(name, env)
+	return ok
+}
@@ -0,0 +1,120 @@
+package eval
+
+import (
+	"fmt"
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+	"github.com/bundgaard/js/token"
+	"runtime/debug"
+)
+
+// PanicError is returned by Run when evaluation panicked. It is a bug in the
+// interpreter or in a builtin, never in the script, but it is reported
+// instead of crashing the host program.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Position is the innermost node being evaluated when the panic happened.
+	Position token.Position
+	// Stack is the Go stack of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	if e.Position.IsValid() {
+		return fmt.Sprintf("%s: panic: %v", e.Position, e.Value)
+	}
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Run evaluates program in environment. An uncaught script error is returned
+// as the *object.Error. Unlike Eval, Run never panics: a panic during
+// evaluation is returned as a *PanicError. An environment without a budget
+// is given object.NewBudget, so deep recursion fails with a RangeError.
+// Several goroutines may run in an environment from
+// object.NewSharedEnvironment at once.
+func Run(program ast.Node, environment *object.Environment) (result object.Object, err error) {
+	defer recoverPanic(&result, &err)
+	return completion(Eval(program, RunEnvironment(environment)))
+}
+
+// RunEnvironment returns the scope to run a script in environment in. That
+// is environment itself, given object.NewBudget if it has no budget, unless
+// environment is shared: each run in a shared environment gets a run scope
+// with a budget forked from environment's.
+func RunEnvironment(environment *object.Environment) *object.Environment {
+	if environment.Shared() {
+		return object.NewRunEnvironment(environment, environment.Budget().Fork())
+	}
+	if environment.Budget() == nil {
+		environment.SetBudget(object.NewBudget())
+	}
+	return environment
+}
+
+// Call calls fn, a script function, compiled or not, or a builtin, with
+// this bound to this and the given arguments. Errors are reported as by Run,
+// so a script function can be used as a callback from Go. Every call is held
+// to a budget of its own with the limits of the scope fn was created in, but
+// not its Context, or to object.NewBudget if that scope has none.
+func Call(fn object.Object, this object.Object, args ...object.Object) (object.Object, error) {
+	var env *object.Environment
+	switch fn := fn.(type) {
+	case *object.Function:
+		env = fn.Environment
+	case *object.Closure:
+		env = fn.Environment
+	}
+	budget := object.NewBudget()
+	if env != nil && env.Budget() != nil {
+		budget = env.Budget().Fork()
+		budget.Context = nil
+	}
+	return CallBudget(budget, fn, this, args...)
+}
+
+// CallBudget is Call holding the call to budget instead.
+func CallBudget(budget *object.Budget, fn object.Object, this object.Object, args ...object.Object) (result object.Object, err error) {
+	defer recoverPanic(&result, &err)
+	switch fn.(type) {
+	case *object.Function, *object.Closure, *object.BuiltinObject:
+	default:
+		return nil, newTypeError("%s is not a function", inspect(fn))
+	}
+	return completion(applyFunction(fn, this, args, budget))
+}
+
+// completion splits the outcome of an evaluation into a result or an error.
+func completion(obj object.Object) (object.Object, error) {
+	if err, ok := obj.(*object.Error); ok {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// recoverPanic turns a panic into a *PanicError returned through err. It
+// must be deferred.
+func recoverPanic(result *object.Object, err *error) {
+	if r := recover(); r != nil {
+		perr, ok := r.(*PanicError)
+		if !ok {
+			perr = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		*result, *err = nil, perr
+	}
+}
+
+// annotatePanic records where in the script a panic started. It is deferred
+// by Eval, so the innermost node wins.
+func annotatePanic(n ast.Node) {
+	if r := recover(); r != nil {
+		if perr, ok := r.(*PanicError); ok {
+			panic(perr)
+		}
+		perr := &PanicError{Value: r, Stack: debug.Stack()}
+		if n != nil {
+			perr.Position = n.Pos()
+		}
+		panic(perr)
+	}
+}
@@ -0,0 +1,53 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+)
+
+// evalSpreadElement returns the values ...argument stands for in a call or
+// an array literal: the elements of an array or the characters of a string.
+func evalSpreadElement(spread *ast.SpreadElement, environment *object.Environment) []object.Object {
+	argument := Eval(spread.Argument, environment)
+	if isError(argument) {
+		return []object.Object{argument}
+	}
+
+	next := valuesOf(argument)
+	if next == nil {
+		err := NotIterable(argument)
+		err.Position = spread.Pos()
+		return []object.Object{err}
+	}
+
+	var values []object.Object
+	for i := 0; ; i++ {
+		value, ok := next(i)
+		if !ok {
+			return values
+		}
+		values = append(values, value)
+	}
+}
+
+// spreadProperties copies the properties of obj into pairs for {...obj}.
+// Arrays and strings contribute their indexes; null, undefined and other
+// values contribute nothing.
+func spreadProperties(obj object.Object, pairs map[object.HashKey]object.HashPair) {
+	switch v := obj.(type) {
+	case *object.Hash:
+		for key, pair := range v.Pairs {
+			pairs[key] = pair
+		}
+	case *object.Array, *object.StringObject:
+		next := valuesOf(v)
+		for i := 0; ; i++ {
+			value, ok := next(i)
+			if !ok {
+				return
+			}
+			key := &object.NumberObject{Value: float64(i)}
+			pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+	"strings"
+)
+
+func evalTemplateLiteral(n *ast.TemplateLiteral, env *object.Environment) object.Object {
+	var out strings.Builder
+	for idx, s := range n.Strings {
+		out.WriteString(s)
+		if idx < len(n.Expressions) {
+			value := Eval(n.Expressions[idx], env)
+			if isError(value) {
+				return value
+			}
+			out.WriteString(toString(value))
+		}
+	}
+	if err := allocate(env, out.Len()); err != nil {
+		return err
+	}
+	return &object.StringObject{Value: out.String()}
+}
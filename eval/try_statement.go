@@ -0,0 +1,122 @@
+package eval
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/object"
+	"github.com/bundgaard/js/token"
+)
+
+func evalThrowStatement(node *ast.ThrowStatement, environment *object.Environment) object.Object {
+	value := Eval(node.Argument, environment)
+	if isError(value) {
+		return value
+	}
+	return throwValue(value)
+}
+
+// throwValue wraps a thrown value in an error. Error objects lend it their
+// name and message so an uncaught exception reads like a runtime error.
+func throwValue(value object.Object) *object.Error {
+	err := &object.Error{Message: inspect(value), Value: value}
+	if hash, ok := value.(*object.Hash); ok && isErrorObject(hash) {
+		name, _ := hash.GetProperty("name")
+		message, _ := hash.GetProperty("message")
+		err.Name = name.(*object.StringObject).Value
+		err.Message = message.(*object.StringObject).Value
+	}
+	return err
+}
+
+// catchValue returns the value a catch clause binds for err: the thrown
+// value, or an error object describing an error raised by the interpreter.
+func catchValue(err *object.Error) object.Object {
+	if err.Value == nil {
+		name := err.Name
+		if name == "" {
+			name = "Error"
+		}
+		errorObject := newErrorObject(name, err.Message)
+		errorObject.SetProperty("stack", &object.StringObject{Value: err.Trace()})
+		return errorObject
+	}
+
+	// An error object learns where it was thrown from once it is caught.
+	if hash, ok := err.Value.(*object.Hash); ok && isErrorObject(hash) {
+		hash.SetProperty("stack", &object.StringObject{Value: err.Trace()})
+	}
+	return err.Value
+}
+
+// evalTryStatement runs the finally block whatever happens in the try and
+// catch blocks. A return, break, continue or throw in the finally block
+// takes precedence over how the rest of the statement completed.
+func evalTryStatement(node *ast.TryStatement, environment *object.Environment) object.Object {
+	result := Eval(node.Block, environment)
+	if err, ok := result.(*object.Error); ok && err.Uncatchable {
+		return err
+	}
+
+	if err, ok := result.(*object.Error); ok && node.Handler != nil {
+		scope := object.NewBlockEnvironment(environment)
+		if node.Param != nil {
+			scope.Set(node.Param.Value, catchValue(err))
+		}
+		result = Eval(node.Handler, scope)
+		if err, ok := result.(*object.Error); ok && err.Uncatchable {
+			return err
+		}
+	}
+
+	if node.Finalizer != nil {
+		completion := Eval(node.Finalizer, environment)
+		if completion != nil {
+			switch completion.Type() {
+			case object.ReturnValueType, object.ErrorType, object.BreakType, object.ContinueType:
+				return completion
+			}
+		}
+	}
+	return result
+}
+
+// evalNewExpression calls a constructor. A builtin constructor returns the
+// object it creates; a function is called with this bound to a new object,
+// which is the result unless the function returns an object of its own.
+func evalNewExpression(node *ast.NewExpression, environment *object.Environment) object.Object {
+	callee := Eval(node.Callee, environment)
+	if isError(callee) {
+		return callee
+	}
+
+	args := evalExpressions(node.Arguments, environment)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	return construct(callee, node.Callee.String(), args, environment.Budget(), node.Pos())
+}
+
+// construct calls the constructor callee, written name in the source, from
+// position call of a run held to budget.
+func construct(callee object.Object, name string, args []object.Object, budget *object.Budget, call token.Position) object.Object {
+	switch fn := callee.(type) {
+	case *object.BuiltinObject:
+		return applyFunction(fn, nil, args, budget)
+	case *object.Function, *object.Closure:
+		if isArrowFunction(fn) {
+			return newTypeError("%s is not a constructor", name)
+		}
+		this := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+		result := applyFunction(fn, this, args, budget)
+		if err, ok := result.(*object.Error); ok {
+			unwind(err, fn, call)
+			return err
+		}
+		if hash, ok := result.(*object.Hash); ok {
+			return hash
+		}
+		return this
+	default:
+		return newTypeError("%s is not a constructor", name)
+	}
+}
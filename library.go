@@ -12,3 +12,21 @@ func New(data string) (object.Object, *object.Environment) {
 	e := object.NewEnvironment()
 	return eval.Eval(p.Parse(), e), e
 }
+
+// Run parses and evaluates data in environment, or in a new environment when
+// environment is nil. A script that does not parse is not run; its syntax
+// errors are returned as a parser.ErrorList. An uncaught script error is
+// returned as an *object.Error, and a panic inside the interpreter as an
+// *eval.PanicError.
+func Run(data string, environment *object.Environment) (object.Object, error) {
+	p := parser.New(strings.NewReader(data))
+	program := p.Parse()
+	if err := p.Errors().Err(); err != nil {
+		return nil, err
+	}
+
+	if environment == nil {
+		environment = object.NewEnvironment()
+	}
+	return eval.Run(program, environment)
+}
@@ -1,7 +1,11 @@
 package js
 
 import (
+	"fmt"
 	"github.com/bundgaard/js/object"
+	"github.com/bundgaard/js/parser"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -21,3 +25,93 @@ println(z);`
 		t.Errorf("expected %q to be %q. got %q", "x", 50, x.Inspect())
 	}
 }
+
+func TestLibraryNewCallDepth(t *testing.T) {
+	// New runs without a budget; recursion still ends with a RangeError.
+	result, _ := New(`function f(n) { return f(n + 1); } f(0)`)
+	err, ok := result.(*object.Error)
+	if !ok || err.Name != "RangeError" || !strings.Contains(err.Message, "call stack") {
+		t.Errorf("expected a RangeError. got %v", result)
+	}
+}
+
+func TestLibraryRun(t *testing.T) {
+	result, err := Run(`var x = 40; x + 2`, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.Inspect() != "42" {
+		t.Errorf("expected %q. got %q", "42", result.Inspect())
+	}
+
+	environment := object.NewEnvironment()
+	if _, err := Run(`var y = 1;`, environment); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result, err := Run(`y + 1`, environment); err != nil || result.Inspect() != "2" {
+		t.Errorf("expected the environment to be reused. got %v, %v", result, err)
+	}
+}
+
+func TestLibraryRunErrors(t *testing.T) {
+	_, err := Run("var x = ;", nil)
+	if _, ok := err.(parser.ErrorList); !ok {
+		t.Errorf("expected a parser.ErrorList. got %T %v", err, err)
+	}
+
+	environment := object.NewEnvironment()
+	_, err = Run("var a = 1;\nthrow new TypeError(\"bad\");\nvar b = 2;", environment)
+	scriptErr, ok := err.(*object.Error)
+	if !ok {
+		t.Fatalf("expected an *object.Error. got %T %v", err, err)
+	}
+	if scriptErr.Error() != "2:1: TypeError: bad" {
+		t.Errorf("expected %q. got %q", "2:1: TypeError: bad", scriptErr.Error())
+	}
+	if _, ok := environment.Get("b"); ok {
+		t.Errorf("expected the script to stop at the uncaught error")
+	}
+}
+
+func TestLibraryRunShared(t *testing.T) {
+	environment := object.NewSharedEnvironment()
+	if _, err := Run(`fn square(x) { return x * x; }`, environment); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	square, _ := environment.Get("square")
+	var goSquare func(float64) float64
+	if err := ExportTo(square, &goSquare); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	program, err := Compile(`square(3) + square(4);`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("x%d", i)
+			if _, err := Run(fmt.Sprintf(`let %s = square(%d);`, name, i), environment); err != nil {
+				errs <- err
+			}
+			if x, ok := environment.Get(name); !ok || x.Inspect() != fmt.Sprint(i*i) {
+				errs <- fmt.Errorf("expected %s to be %d. got %v", name, i*i, x)
+			}
+			if result, err := program.Run(environment); err != nil || result.Inspect() != "25" {
+				errs <- fmt.Errorf("expected 25. got %v, %v", result, err)
+			}
+			if n := goSquare(float64(i)); n != float64(i*i) {
+				errs <- fmt.Errorf("expected %d. got %v", i*i, n)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -6,15 +6,23 @@ type Array struct {
 	Elements []Object
 }
 
-func (ao *Array) Type() Type { return ArrayType }
-func (ao *Array) Inspect() string {
+func (ao *Array) Type() Type      { return ArrayType }
+func (ao *Array) Inspect() string { return ao.inspect(map[Object]bool{}) }
+
+func (ao *Array) inspect(seen map[Object]bool) string {
+	if seen[ao] {
+		return "[Circular]"
+	}
+	seen[ao] = true
+	defer delete(seen, ao)
+
 	var (
 		out      strings.Builder
 		elements []string
 	)
 
 	for _, e := range ao.Elements {
-		elements = append(elements, e.Inspect())
+		elements = append(elements, inspectNested(e, seen))
 	}
 
 	out.WriteString("[")
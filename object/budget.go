@@ -0,0 +1,108 @@
+package object
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultMaxCallDepth is the call depth NewBudget allows. It keeps a runaway
+// recursion well clear of the Go stack limit.
+const DefaultMaxCallDepth = 10000
+
+// Budget limits the work a script may do. It is carried by the environment
+// the script runs in and shared by every scope created from it. A zero limit
+// means no limit. A budget counts the usage of a single run, so it must not
+// be shared by runs on different goroutines; Fork gives each its own.
+type Budget struct {
+	// Context cancels evaluation when it is done.
+	Context context.Context
+	// MaxSteps is the number of nodes evaluation may visit.
+	MaxSteps int64
+	// MaxCallDepth is the number of nested function calls allowed.
+	MaxCallDepth int
+	// MaxAllocation caps the total size of the strings, arrays and objects
+	// a script creates: a byte per character, a unit per array element or
+	// object entry. Memory is never given back to the budget.
+	MaxAllocation int64
+
+	steps     int64
+	depth     int
+	allocated int64
+}
+
+// NewBudget returns a budget with no limits other than DefaultMaxCallDepth.
+func NewBudget() *Budget {
+	return &Budget{MaxCallDepth: DefaultMaxCallDepth}
+}
+
+// Fork returns a new budget with the limits and Context of b and nothing
+// used, for a run that must not share b's usage.
+func (b *Budget) Fork() *Budget {
+	return &Budget{
+		Context:       b.Context,
+		MaxSteps:      b.MaxSteps,
+		MaxCallDepth:  b.MaxCallDepth,
+		MaxAllocation: b.MaxAllocation,
+	}
+}
+
+// contextCheckInterval is how many steps pass between checks of Context.
+const contextCheckInterval = 1024
+
+// Step accounts for the evaluation of one node.
+func (b *Budget) Step() *Error {
+	b.steps++
+	if b.MaxSteps > 0 && b.steps > b.MaxSteps {
+		return abort("step limit of %d exceeded", b.MaxSteps)
+	}
+	if b.Context != nil && b.steps%contextCheckInterval == 0 {
+		return b.checkContext()
+	}
+	return nil
+}
+
+func (b *Budget) checkContext() *Error {
+	if err := b.Context.Err(); err != nil {
+		return abort("evaluation cancelled: %s", err)
+	}
+	return nil
+}
+
+// Enter accounts for a function call. Every successful Enter must be
+// matched by a Leave; a failed one changes nothing.
+func (b *Budget) Enter() *Error {
+	if b.MaxCallDepth > 0 && b.depth >= b.MaxCallDepth {
+		return &Error{Name: "RangeError", Message: "maximum call stack size exceeded"}
+	}
+	if b.Context != nil {
+		if err := b.checkContext(); err != nil {
+			return err
+		}
+	}
+	b.depth++
+	return nil
+}
+
+// Leave accounts for the return from a function call.
+func (b *Budget) Leave() {
+	b.depth--
+}
+
+// Allocate accounts for a new value of the given size.
+func (b *Budget) Allocate(size int) *Error {
+	b.allocated += int64(size)
+	if b.MaxAllocation > 0 && b.allocated > b.MaxAllocation {
+		return abort("allocation limit of %d exceeded", b.MaxAllocation)
+	}
+	return nil
+}
+
+// Reset clears what has been used of the budget, keeping the limits.
+func (b *Budget) Reset() {
+	b.steps, b.depth, b.allocated = 0, 0, 0
+}
+
+// abort returns an error that ends the script. Scripts cannot catch it.
+func abort(format string, v ...interface{}) *Error {
+	return &Error{Name: "RangeError", Message: fmt.Sprintf(format, v...), Uncatchable: true}
+}
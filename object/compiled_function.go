@@ -0,0 +1,45 @@
+package object
+
+import "github.com/bundgaard/js/code"
+
+// CompiledFunction is a function, or a whole program, compiled to bytecode.
+// It is immutable, so it can be shared by virtual machines running on
+// several goroutines.
+type CompiledFunction struct {
+	Name       string
+	Parameters []string
+	Rest       string // name of the rest parameter, if any
+	Arrow      bool
+	// Bound is the number of Parameters bound before the function runs. The
+	// function binds the others itself, in order, since their defaults see
+	// the parameters before them.
+	Bound int
+	// UsesArguments and UsesThis are set when the function, or an arrow
+	// function in it, refers to arguments or this, which then have to be
+	// bound for each call.
+	UsesArguments bool
+	UsesThis      bool
+	// Source is the function as a tree-walking Function would print it.
+	Source string
+
+	Instructions code.Instructions
+	Constants    []Object
+	// Positions maps instructions back to the source for error messages,
+	// and Calls maps call instructions to the position of the callee for
+	// stack traces.
+	Positions code.Positions
+	Calls     code.Positions
+}
+
+func (cf *CompiledFunction) Type() Type      { return CompiledFunctionType }
+func (cf *CompiledFunction) Inspect() string { return cf.Source }
+
+// Closure is a compiled function together with the environment it was
+// created in.
+type Closure struct {
+	Function    *CompiledFunction
+	Environment *Environment
+}
+
+func (c *Closure) Type() Type      { return FunctionType }
+func (c *Closure) Inspect() string { return c.Function.Source }
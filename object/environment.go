@@ -1,9 +1,25 @@
 package object
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 type Environment struct {
-	store map[string]Object
+	store     map[string]Object
+	lexical   map[string]bool // declared with let or const
+	constants map[string]bool
+	block     bool
+	budget    *Budget
+	// mu guards the bindings of NewSharedEnvironment and of the scopes
+	// created from it. It is nil in every other scope, which only one
+	// goroutine may use at a time.
+	mu *sync.RWMutex
+	// shared is set in the scope NewSharedEnvironment returns.
+	shared bool
+	// run is set in a scope from NewRunEnvironment, which binds nothing
+	// itself.
+	run   bool
 	Outer *Environment
 }
 
@@ -12,13 +28,83 @@ func NewEnvironment() *Environment {
 	return &Environment{store: e}
 }
 
+// NewSharedEnvironment returns a global scope that scripts on any number of
+// goroutines may run in at once. Every scope created from it synchronizes
+// its bindings, so closures that escape into it can be called concurrently
+// too. Runs in a shared scope get a scope and a budget of their own from
+// NewRunEnvironment; the budget given here holds the limits they fork.
+//
+// Only bindings are synchronized. Arrays and objects that several scripts
+// change at once must be guarded by the embedder, and a read followed by a
+// write, as in x++, is not atomic.
+func NewSharedEnvironment() *Environment {
+	env := NewEnvironment()
+	env.mu = &sync.RWMutex{}
+	env.shared = true
+	env.budget = NewBudget()
+	return env
+}
+
+// NewRunEnvironment returns the scope of a single run in outer. It binds
+// nothing itself: declarations and Set go to outer. It only carries the
+// budget of the run, so runs sharing outer do not share their usage.
+func NewRunEnvironment(outer *Environment, budget *Budget) *Environment {
+	return &Environment{block: true, run: true, budget: budget, mu: outer.mu, Outer: outer}
+}
+
+// Shared reports whether e was created by NewSharedEnvironment.
+func (e *Environment) Shared() bool {
+	return e.shared
+}
+
+// bindings returns the scope whose bindings e stands for, which is e itself
+// unless it is a run scope.
+func (e *Environment) bindings() *Environment {
+	for e.run {
+		e = e.Outer
+	}
+	return e
+}
+
+func (e *Environment) lock() {
+	if e.mu != nil {
+		e.mu.Lock()
+	}
+}
+
+func (e *Environment) unlock() {
+	if e.mu != nil {
+		e.mu.Unlock()
+	}
+}
+
+func (e *Environment) rlock() {
+	if e.mu != nil {
+		e.mu.RLock()
+	}
+}
+
+func (e *Environment) runlock() {
+	if e.mu != nil {
+		e.mu.RUnlock()
+	}
+}
+
+// Get looks name up in this scope and then in each enclosing scope.
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	return obj, ok
+	for env := e; env != nil; env = env.Outer {
+		env.rlock()
+		obj, ok := env.store[name]
+		env.runlock()
+		if ok {
+			return obj, true
+		}
+	}
+	return nil, false
 }
 
 func (e *Environment) GetString(name string) (string, error) {
-	obj, ok := e.store[name]
+	obj, ok := e.Get(name)
 	if !ok {
 		return "", fmt.Errorf("%q is not found", name)
 	}
@@ -32,7 +118,7 @@ func (e *Environment) GetString(name string) (string, error) {
 }
 
 func (e *Environment) GetBool(name string) (bool, error) {
-	obj, ok := e.store[name]
+	obj, ok := e.Get(name)
 	if !ok {
 		return false, fmt.Errorf("%q is not in environment", name)
 	}
@@ -45,13 +131,120 @@ func (e *Environment) GetBool(name string) (bool, error) {
 	}
 }
 
+// Set binds name in this scope, shadowing any binding in an enclosing scope.
 func (e *Environment) Set(name string, val Object) Object {
+	return e.set(name, val, false, false)
+}
+
+// SetLexical binds name in this scope the way let does. Lexical bindings may
+// not be declared twice in the same scope.
+func (e *Environment) SetLexical(name string, val Object) Object {
+	return e.set(name, val, true, false)
+}
+
+// SetConst binds name in this scope the way const does.
+func (e *Environment) SetConst(name string, val Object) Object {
+	return e.set(name, val, true, true)
+}
+
+func (e *Environment) set(name string, val Object, lexical, constant bool) Object {
+	e = e.bindings()
+	val = Normalize(val)
+	e.lock()
+	defer e.unlock()
+	if lexical {
+		if e.lexical == nil {
+			e.lexical = make(map[string]bool)
+		}
+		e.lexical[name] = true
+	}
+	if constant {
+		if e.constants == nil {
+			e.constants = make(map[string]bool)
+		}
+		e.constants[name] = true
+	}
 	e.store[name] = val
 	return val
 }
 
+// Has reports whether name is bound in this scope, ignoring enclosing scopes.
+func (e *Environment) Has(name string) bool {
+	e = e.bindings()
+	e.rlock()
+	defer e.runlock()
+	_, ok := e.store[name]
+	return ok
+}
+
+// IsLexical reports whether name was bound in this scope by let or const.
+func (e *Environment) IsLexical(name string) bool {
+	e = e.bindings()
+	e.rlock()
+	defer e.runlock()
+	return e.lexical[name]
+}
+
+// IsConst reports whether name was bound in this scope by const.
+func (e *Environment) IsConst(name string) bool {
+	e = e.bindings()
+	e.rlock()
+	defer e.runlock()
+	return e.constants[name]
+}
+
+// Assign updates the nearest existing binding of name in the scope chain.
+// Assigning to an undeclared name is a ReferenceError and to a constant a
+// TypeError.
+func (e *Environment) Assign(name string, val Object) *Error {
+	val = Normalize(val)
+	for env := e; env != nil; env = env.Outer {
+		if ok, err := env.assign(name, val); ok {
+			return err
+		}
+	}
+	return &Error{Name: "ReferenceError", Message: fmt.Sprintf("%q is not defined", name)}
+}
+
+// assign updates the binding of name in this scope, if there is one.
+func (e *Environment) assign(name string, val Object) (bool, *Error) {
+	e.lock()
+	defer e.unlock()
+	if _, ok := e.store[name]; !ok {
+		return false, nil
+	}
+	if e.constants[name] {
+		return true, &Error{Name: "TypeError", Message: fmt.Sprintf("assignment to constant variable %q", name)}
+	}
+	e.store[name] = val
+	return true, nil
+}
+
+// FunctionScope returns the nearest enclosing scope that is not a block,
+// which is where var declarations live.
+func (e *Environment) FunctionScope() *Environment {
+	env := e
+	for env.block && env.Outer != nil {
+		env = env.Outer
+	}
+	return env
+}
+
+// ForEach calls iterator for every binding in this scope. Enclosing scopes
+// are not visited.
 func (e *Environment) ForEach(iterator func(key string, value Object)) {
-	for k, v := range e.store {
+	e = e.bindings()
+	e.rlock()
+	store := e.store
+	if e.mu != nil {
+		// Call iterator on a copy, so it may change the scope.
+		store = make(map[string]Object, len(e.store))
+		for k, v := range e.store {
+			store[k] = v
+		}
+	}
+	e.runlock()
+	for k, v := range store {
 		iterator(k, v)
 	}
 }
@@ -59,5 +252,61 @@ func (e *Environment) ForEach(iterator func(key string, value Object)) {
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.Outer = outer
+	env.budget = outer.budget
+	if outer.mu != nil {
+		env.mu = &sync.RWMutex{}
+	}
+	return env
+}
+
+// SetBudget limits the scripts run in this scope. Scopes created from it
+// afterwards, including those of functions declared in it, share the budget,
+// so set it before running any script.
+func (e *Environment) SetBudget(b *Budget) {
+	e.budget = b
+}
+
+// Budget returns the budget of this scope, or nil if it has none.
+func (e *Environment) Budget() *Budget {
+	return e.budget
+}
+
+// NewBlockEnvironment creates the scope for a block. It holds let and const
+// bindings while var declarations pass through to the function scope.
+func NewBlockEnvironment(outer *Environment) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.block = true
+	return env
+}
+
+// Copy returns a new scope with the same bindings and the same enclosing
+// scope. Loops use it to give every iteration its own let bindings.
+func (e *Environment) Copy() *Environment {
+	e.rlock()
+	defer e.runlock()
+	env := &Environment{
+		store:  make(map[string]Object, len(e.store)),
+		block:  e.block,
+		budget: e.budget,
+		Outer:  e.Outer,
+	}
+	if e.mu != nil {
+		env.mu = &sync.RWMutex{}
+	}
+	for k, v := range e.store {
+		env.store[k] = v
+	}
+	if e.lexical != nil {
+		env.lexical = make(map[string]bool, len(e.lexical))
+		for k, v := range e.lexical {
+			env.lexical[k] = v
+		}
+	}
+	if e.constants != nil {
+		env.constants = make(map[string]bool, len(e.constants))
+		for k, v := range e.constants {
+			env.constants[k] = v
+		}
+	}
 	return env
 }
@@ -1,8 +1,97 @@
 package object
 
+import (
+	"github.com/bundgaard/js/token"
+	"strings"
+)
+
 type Error struct {
+	// Name is the kind of error, such as TypeError. It is empty for a plain
+	// Error and for thrown values that are not error objects.
+	Name    string
 	Message string
+	// Value is the value the script threw. It is nil for errors raised by
+	// the interpreter.
+	Value Object
+	// Position is where in the source the error was raised.
+	Position token.Position
+	// Stack lists the calls the error propagated through, innermost first.
+	Stack []Frame
+	// Uncatchable errors, such as an exhausted Budget, end the script
+	// without running catch or finally blocks.
+	Uncatchable bool
+}
+
+// Frame is an entry of an error's stack trace: a position inside the named
+// function, or in the top level of the script when Function is empty.
+type Frame struct {
+	Function string
+	Position token.Position
+}
+
+func (e *Error) Type() Type { return ErrorType }
+func (e *Error) Inspect() string {
+	var out strings.Builder
+	out.WriteString("ERROR: ")
+	if e.Position.IsValid() {
+		out.WriteString(e.Position.String() + ": ")
+	}
+	if e.Name != "" {
+		out.WriteString(e.Name + ": ")
+	}
+	out.WriteString(e.Message)
+	e.writeStack(&out)
+	return out.String()
+}
+
+// Error formats the error without its stack, so *Error can be returned to Go
+// callers as an error.
+func (e *Error) Error() string {
+	var out strings.Builder
+	if e.Position.IsValid() {
+		out.WriteString(e.Position.String() + ": ")
+	}
+	if e.Name != "" {
+		out.WriteString(e.Name + ": ")
+	}
+	out.WriteString(e.Message)
+	return out.String()
+}
+
+// Trace formats the error like the stack property of a script error object:
+// the name and message followed by the calls it propagated through.
+func (e *Error) Trace() string {
+	var out strings.Builder
+	if e.Name != "" {
+		out.WriteString(e.Name)
+	} else {
+		out.WriteString("Error")
+	}
+	out.WriteString(": " + e.Message)
+	if len(e.Stack) == 0 && e.Position.IsValid() {
+		out.WriteString("\n    at " + e.Position.String())
+	}
+	e.writeStack(&out)
+	return out.String()
 }
 
-func (e *Error) Type() Type      { return ErrorType }
-func (e *Error) Inspect() string { return "ERROR: " + e.Message }
+func (e *Error) writeStack(out *strings.Builder) {
+	for _, frame := range e.Stack {
+		out.WriteString("\n    at ")
+		if frame.Function != "" {
+			out.WriteString(frame.Function + " (" + frame.Position.String() + ")")
+		} else {
+			out.WriteString(frame.Position.String())
+		}
+	}
+}
+
+// Unwind records that the error propagated out of a call to function made at
+// position call.
+func (e *Error) Unwind(function string, call token.Position) {
+	if len(e.Stack) == 0 {
+		e.Stack = append(e.Stack, Frame{Position: e.Position})
+	}
+	e.Stack[len(e.Stack)-1].Function = function
+	e.Stack = append(e.Stack, Frame{Position: call})
+}
@@ -10,16 +10,25 @@ type Function struct {
 	Token       *token.Token
 	Name        string
 	Parameters  []*ast.Identifier
+	Defaults    []ast.Expression
+	Rest        *ast.Identifier
 	Body        *ast.BlockStatement
 	Environment *Environment
+	// Arrow functions have no this of their own; they see the this of the
+	// scope they were created in.
+	Arrow bool
 }
 
 func (fl *Function) Type() Type { return FunctionType }
 func (fl *Function) Inspect() string {
 	var out strings.Builder
-	var params []string
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	params := (&ast.FunctionLiteral{Parameters: fl.Parameters, Defaults: fl.Defaults, Rest: fl.Rest}).ParameterStrings()
+	if fl.Arrow {
+		out.WriteString("(")
+		out.WriteString(strings.Join(params, ", "))
+		out.WriteString(") => ")
+		out.WriteString(fl.Body.String())
+		return out.String()
 	}
 	out.WriteString(fl.Token.Value)
 	out.WriteString("(")
@@ -24,8 +24,16 @@ type Hash struct {
 	Pairs map[HashKey]HashPair
 }
 
-func (h *Hash) Type() Type { return HashType }
-func (h *Hash) Inspect() string {
+func (h *Hash) Type() Type      { return HashType }
+func (h *Hash) Inspect() string { return h.inspect(map[Object]bool{}) }
+
+func (h *Hash) inspect(seen map[Object]bool) string {
+	if seen[h] {
+		return "[Circular]"
+	}
+	seen[h] = true
+	defer delete(seen, h)
+
 	var (
 		out   bytes.Buffer
 		pairs []string
@@ -33,7 +41,7 @@ func (h *Hash) Inspect() string {
 
 	for _, pair := range h.Pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
-			pair.Key.Inspect(), pair.Value.Inspect()))
+			pair.Key.Inspect(), inspectNested(pair.Value, seen)))
 	}
 
 	out.WriteString("{")
@@ -2,6 +2,13 @@ package object
 
 import "fmt"
 
+// Integer is an integral number.
+//
+// Deprecated: every number is a NumberObject. Integer values that reach the
+// interpreter through an Environment, a builtin's result or a property lookup
+// are converted with Normalize, and an Integer hashes like the NumberObject of
+// the same value, so existing embedders keep working. New code should
+// construct NumberObject directly.
 type Integer struct {
 	Value int64
 }
@@ -9,5 +16,19 @@ type Integer struct {
 func (i *Integer) Type() Type      { return IntegerType }
 func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
 func (i *Integer) HashKey() HashKey {
-	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+	return i.Number().HashKey()
+}
+
+// Number returns the NumberObject with the same value as i.
+func (i *Integer) Number() *NumberObject {
+	return &NumberObject{Value: float64(i.Value)}
+}
+
+// Normalize returns obj with a deprecated Integer replaced by the equivalent
+// NumberObject. Any other value is returned unchanged.
+func Normalize(obj Object) Object {
+	if i, ok := obj.(*Integer); ok {
+		return i.Number()
+	}
+	return obj
 }
@@ -0,0 +1,13 @@
+package object
+
+// Break and Continue are produced by break and continue statements and travel
+// up through block evaluation, like ReturnValue, until a loop consumes them.
+type Break struct{}
+
+func (b *Break) Type() Type      { return BreakType }
+func (b *Break) Inspect() string { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() Type      { return ContinueType }
+func (c *Continue) Inspect() string { return "continue" }
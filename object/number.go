@@ -1,18 +1,51 @@
 package object
 
 import (
-	"fmt"
-	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
 )
 
+// NumberObject is a JavaScript number, an IEEE-754 double.
 type NumberObject struct {
-	Value int64
+	Value float64
 }
 
 func (n *NumberObject) Type() Type      { return NumberType }
-func (n *NumberObject) Inspect() string { return fmt.Sprintf("%d", n.Value) }
+func (n *NumberObject) Inspect() string { return FormatNumber(n.Value) }
 func (n *NumberObject) HashKey() HashKey {
-	h := fnv.New64a()
-	fmt.Fprintf(h, "%d", n.Value)
-	return HashKey{Type: n.Type(), Value: h.Sum64()}
+	v := n.Value
+	switch {
+	case v == 0:
+		v = 0 // -0 and +0 are the same key
+	case math.IsNaN(v):
+		v = math.NaN()
+	}
+	return HashKey{Type: n.Type(), Value: math.Float64bits(v)}
+}
+
+// FormatNumber formats f the way JavaScript converts numbers to strings:
+// integers have no fraction, very large and very small magnitudes use
+// exponent notation, and the special values are NaN and Infinity.
+func FormatNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f == 0:
+		return "0"
+	}
+
+	abs := math.Abs(f)
+	if abs >= 1e21 || abs < 1e-6 {
+		// Go writes 1.5e-07 where JavaScript writes 1.5e-7.
+		s := strconv.FormatFloat(f, 'e', -1, 64)
+		mantissa, exponent := s[:strings.IndexByte(s, 'e')], s[strings.IndexByte(s, 'e')+1:]
+		sign, digits := exponent[:1], strings.TrimLeft(exponent[1:], "0")
+		return mantissa + "e" + sign + digits
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
 }
@@ -9,6 +9,19 @@ type Object interface {
 	Inspect() string
 }
 
+// inspectNested formats obj, an element of an array or a property of an
+// object. An array or object that contains itself, which seen tracks, is
+// [Circular] inside itself.
+func inspectNested(obj Object, seen map[Object]bool) string {
+	switch v := obj.(type) {
+	case *Array:
+		return v.inspect(seen)
+	case *Hash:
+		return v.inspect(seen)
+	}
+	return obj.Inspect()
+}
+
 //go:generate stringer -type ObjectType
 type Type uint8
 
@@ -25,4 +38,8 @@ const (
 	BuiltinType
 	FunctionType
 	BooleanType
+	BreakType
+	ContinueType
+	UndefinedType
+	CompiledFunctionType
 )
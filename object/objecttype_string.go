@@ -19,11 +19,15 @@ func _() {
 	_ = x[BuiltinType-9]
 	_ = x[FunctionType-10]
 	_ = x[BooleanType-11]
+	_ = x[BreakType-12]
+	_ = x[ContinueType-13]
+	_ = x[UndefinedType-14]
+	_ = x[CompiledFunctionType-15]
 }
 
-const _ObjectType_name = "NullTypeErrorTypeReturnValueTypeIntegerTypeStringTypeArrayTypeHashTypeNumberTypeBuiltinTypeFunctionTypeBooleanType"
+const _ObjectType_name = "NullTypeErrorTypeReturnValueTypeIntegerTypeStringTypeArrayTypeHashTypeNumberTypeBuiltinTypeFunctionTypeBooleanTypeBreakTypeContinueTypeUndefinedTypeCompiledFunctionType"
 
-var _ObjectType_index = [...]uint8{0, 8, 17, 32, 43, 53, 62, 70, 80, 91, 103, 114}
+var _ObjectType_index = [...]uint8{0, 8, 17, 32, 43, 53, 62, 70, 80, 91, 103, 114, 123, 135, 148, 168}
 
 func (i Type) String() string {
 	i -= 1
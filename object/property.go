@@ -0,0 +1,49 @@
+package object
+
+import "fmt"
+
+// PropertyGetter is implemented by objects that expose named properties to
+// scripts through member access, obj.name. Embedders implement it on their
+// own types to hand scripts structured values.
+type PropertyGetter interface {
+	GetProperty(name string) (Object, bool)
+}
+
+// PropertySetter is implemented by objects whose properties scripts may
+// assign to, obj.name = value.
+type PropertySetter interface {
+	SetProperty(name string, value Object) error
+}
+
+func (h *Hash) GetProperty(name string) (Object, bool) {
+	key := &StringObject{Value: name}
+	pair, ok := h.Pairs[key.HashKey()]
+	if !ok {
+		return nil, false
+	}
+	return pair.Value, true
+}
+
+func (h *Hash) SetProperty(name string, value Object) error {
+	key := &StringObject{Value: name}
+	h.Pairs[key.HashKey()] = HashPair{Key: key, Value: value}
+	return nil
+}
+
+func (ao *Array) GetProperty(name string) (Object, bool) {
+	if name == "length" {
+		return &NumberObject{Value: float64(len(ao.Elements))}, true
+	}
+	return nil, false
+}
+
+func (ao *Array) SetProperty(name string, value Object) error {
+	return fmt.Errorf("cannot set property %q of array", name)
+}
+
+func (s *StringObject) GetProperty(name string) (Object, bool) {
+	if name == "length" {
+		return &NumberObject{Value: float64(len([]rune(s.Value)))}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,9 @@
+package object
+
+// UndefinedObject is the value of the global undefined and of an optional
+// chain that was cut short.
+type UndefinedObject struct {
+}
+
+func (uo *UndefinedObject) Type() Type      { return UndefinedType }
+func (uo *UndefinedObject) Inspect() string { return "undefined" }
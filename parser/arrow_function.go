@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
+
+// parseArrowFunction parses x => body, an arrow function with a single
+// parameter written without parentheses.
+func (p *Parser) parseArrowFunction(left ast.Expression) ast.Expression {
+	param, ok := left.(*ast.Identifier)
+	if !ok {
+		p.error("invalid arrow function parameter %s", left)
+		return nil
+	}
+	fn := &ast.FunctionLiteral{Token: p.current, Parameters: []*ast.Identifier{param}, Arrow: true}
+	return p.parseArrowBody(fn)
+}
+
+// parseArrowBody parses what follows the => of an arrow function: a block,
+// or an expression which becomes the return value of the body.
+func (p *Parser) parseArrowBody(fn *ast.FunctionLiteral) ast.Expression {
+	if p.peekTokenIs(token.OpenCurly) {
+		p.nextToken()
+		fn.Body = p.parseFunctionBody()
+		return fn
+	}
+
+	arrow := p.current
+	p.nextToken()
+	value := p.parseExpression(ast.Lowest)
+	ret := &ast.ReturnStatement{
+		Token:       &token.Token{Type: token.Return, Value: "return", Start: arrow.Start, End: arrow.End},
+		ReturnValue: value,
+	}
+	fn.Body = &ast.BlockStatement{Token: arrow, Statements: []ast.Statement{ret}}
+	return fn
+}
+
+// arrowParameters turns the expressions read between the parentheses before
+// => into the parameters of fn: names, name = default and a final ...rest.
+func (p *Parser) arrowParameters(fn *ast.FunctionLiteral, expressions []ast.Expression) bool {
+	for idx, expression := range expressions {
+		switch e := expression.(type) {
+		case *ast.Identifier:
+			addParameter(fn, e, nil)
+			continue
+		case *ast.AssignExpression:
+			if ident, ok := e.Target.(*ast.Identifier); ok && e.Operator == "=" {
+				addParameter(fn, ident, e.Value)
+				continue
+			}
+		case *ast.SpreadElement:
+			if ident, ok := e.Argument.(*ast.Identifier); ok && idx == len(expressions)-1 {
+				fn.Rest = ident
+				continue
+			}
+		}
+		p.error("invalid arrow function parameter %s", expression)
+		return false
+	}
+	return true
+}
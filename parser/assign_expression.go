@@ -0,0 +1,58 @@
+package parser
+
+import "github.com/bundgaard/js/ast"
+
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	if !isAssignable(target) {
+		p.error("invalid assignment target")
+		return nil
+	}
+
+	expression := &ast.AssignExpression{
+		Token:    p.current,
+		Operator: p.current.Value,
+		Target:   target,
+	}
+
+	// Assignment is right associative: a = b = c is a = (b = c).
+	p.nextToken()
+	expression.Value = p.parseExpression(ast.Assign - 1)
+	return expression
+}
+
+func (p *Parser) parsePrefixUpdateExpression() ast.Expression {
+	expression := &ast.UpdateExpression{
+		Token:    p.current,
+		Operator: p.current.Value,
+		Prefix:   true,
+	}
+
+	p.nextToken()
+	expression.Target = p.parseExpression(ast.Prefix)
+	if !isAssignable(expression.Target) {
+		p.error("invalid %s operand", expression.Operator)
+		return nil
+	}
+	return expression
+}
+
+func (p *Parser) parsePostfixUpdateExpression(target ast.Expression) ast.Expression {
+	if !isAssignable(target) {
+		p.error("invalid %s operand", p.current.Value)
+		return nil
+	}
+
+	return &ast.UpdateExpression{
+		Token:    p.current,
+		Operator: p.current.Value,
+		Target:   target,
+	}
+}
+
+func isAssignable(target ast.Expression) bool {
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression, *ast.MemberExpression:
+		return true
+	}
+	return false
+}
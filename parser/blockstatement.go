@@ -17,5 +17,8 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	if p.currentTokenIs(token.EOF) {
+		p.error("expected %s, found %s", expected(token.CloseCurly), describe(p.current))
+	}
 	return block
 }
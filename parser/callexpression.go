@@ -24,11 +24,11 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(ast.Lowest))
+	args = append(args, p.parseListElement())
 	for p.peekTokenIs(token.Comma) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(ast.Lowest))
+		args = append(args, p.parseListElement())
 	}
 
 	if !p.expectPeek(token.CloseParen) {
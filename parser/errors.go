@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/bundgaard/js/token"
+)
+
+// SyntaxError is a problem found while parsing a script.
+type SyntaxError struct {
+	File    string
+	Line    int
+	Column  int
+	Token   token.Token
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// ErrorList is the list of syntax errors found in a script, in source order.
+type ErrorList []*SyntaxError
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Err returns l as an error, or nil if l is empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Errors returns the syntax errors found by Parse.
+func (p *Parser) Errors() ErrorList {
+	return p.errors
+}
+
+func (p *Parser) errorAt(tok *token.Token, format string, args ...interface{}) {
+	// Only the first error in a statement is reported; the rest are usually
+	// caused by it.
+	if p.failed {
+		return
+	}
+	p.failed = true
+
+	if msg, ok := p.illegal[tok]; ok {
+		format, args = "%s", []interface{}{msg}
+	}
+
+	err := &SyntaxError{
+		File:    p.filename,
+		Line:    tok.Start.Line,
+		Column:  tok.Start.Column,
+		Token:   *tok,
+		Message: fmt.Sprintf(format, args...),
+	}
+	p.errors = append(p.errors, err)
+}
+
+// error reports a syntax error at the current token. An Illegal token is
+// reported with the scanner's description of it instead.
+func (p *Parser) error(format string, args ...interface{}) {
+	p.errorAt(p.current, format, args...)
+}
+
+// peekError reports that the next token is not the expected one.
+func (p *Parser) peekError(expected string) {
+	p.errorAt(p.next, "expected %s, found %s", expected, describe(p.next))
+}
+
+func describe(tok *token.Token) string {
+	if tok.Type == token.EOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", tok.Value)
+}
+
+// synchronize skips the rest of a top-level statement that failed to parse.
+// It stops, outside of any braces, at a semicolon or closing brace, or before
+// a token that starts a new line or a keyword that starts a new statement.
+func (p *Parser) synchronize() {
+	for !p.currentTokenIs(token.EOF) {
+		if p.depth == 0 {
+			if p.currentTokenIs(token.Semi) || p.currentTokenIs(token.CloseCurly) || p.peekOnNewLine() {
+				return
+			}
+			switch p.next.Type {
+			case token.Var, token.Let, token.Const, token.If, token.Return,
+				token.While, token.For, token.Break, token.Continue, token.Function,
+				token.Throw, token.Try:
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
+
+var tokenText = map[token.Type]string{
+	token.Ident:        "name",
+	token.Assign:       `"="`,
+	token.Colon:        `":"`,
+	token.Semi:         `";"`,
+	token.Comma:        `","`,
+	token.OpenParen:    `"("`,
+	token.CloseParen:   `")"`,
+	token.OpenCurly:    `"{"`,
+	token.CloseCurly:   `"}"`,
+	token.OpenBracket:  `"["`,
+	token.CloseBracket: `"]"`,
+}
+
+func expected(t token.Type) string {
+	if text, ok := tokenText[t]; ok {
+		return text
+	}
+	return t.String()
+}
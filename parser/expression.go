@@ -1,15 +1,15 @@
 package parser
 
 import (
-	"fmt"
 	"github.com/bundgaard/js/ast"
 	"github.com/bundgaard/js/token"
-	"log"
-	"os"
-	"time"
 )
 
 func (p *Parser) parseExpression(priority int) ast.Expression {
+	if !p.nest() {
+		return nil
+	}
+	defer p.unnest()
 
 	if p.currentTokenIs(token.CommentLine) || p.currentTokenIs(token.CommentBlock) {
 		p.nextToken()
@@ -17,20 +17,7 @@ func (p *Parser) parseExpression(priority int) ast.Expression {
 
 	prefix := p.prefixParseFns[p.current.Type]
 	if prefix == nil {
-		if counter.Get() == 10 {
-
-			filename := time.Now().Format("20060102T15") + ".debug"
-			log.Println("something weird happened, saved to file", filename)
-			bugFile, err := os.Create(filename)
-			if err != nil {
-				log.Fatal(err)
-			}
-			defer bugFile.Close()
-			fmt.Fprintf(bugFile, "%v; error at (%d,%d)", &p.s.Buf, p.s.Line, p.s.Column)
-			os.Exit(1)
-		}
-		log.Printf("no prefix for %v (%d,%d)", p.current, p.s.Line, p.s.Column)
-		counter.Add()
+		p.error("unexpected %s", describe(p.current))
 		return nil
 	}
 
@@ -41,9 +28,17 @@ func (p *Parser) parseExpression(priority int) ast.Expression {
 		if infix == nil {
 			return leftExp
 		}
+		// ++ and -- on a new line start the next statement rather than
+		// apply to the end of this one.
+		if (p.peekTokenIs(token.Increment) || p.peekTokenIs(token.Decrement)) && p.peekOnNewLine() {
+			return leftExp
+		}
 		p.nextToken()
 
 		leftExp = infix(leftExp)
+		if hasOptionalLink(leftExp) && !p.peekIsChainLink() {
+			leftExp = &ast.OptionalChain{Expression: leftExp}
+		}
 	}
 	// log.Printf("END parse expression %q %q", p.current, leftExp)
 	return leftExp
@@ -14,12 +14,12 @@ func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(ast.Lowest))
+	list = append(list, p.parseListElement())
 
 	for p.peekTokenIs(token.Comma) {
 		p.nextToken() // ,
 		p.nextToken() // Expression
-		list = append(list, p.parseExpression(ast.Lowest))
+		list = append(list, p.parseListElement())
 	}
 
 	if !p.expectPeek(end) {
@@ -27,3 +27,19 @@ func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 	}
 	return list
 }
+
+// parseListElement parses an element of an array literal or an argument of
+// a call, which may be spread with ...
+func (p *Parser) parseListElement() ast.Expression {
+	if p.currentTokenIs(token.Ellipsis) {
+		return p.parseSpreadElement()
+	}
+	return p.parseExpression(ast.Lowest)
+}
+
+func (p *Parser) parseSpreadElement() *ast.SpreadElement {
+	spread := &ast.SpreadElement{Token: p.current}
+	p.nextToken()
+	spread.Argument = p.parseExpression(ast.Lowest)
+	return spread
+}
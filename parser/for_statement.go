@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
+
+func (p *Parser) parseForStatement() ast.Statement {
+	forToken := p.current
+	if !p.expectPeek(token.OpenParen) {
+		return nil
+	}
+	p.nextToken() // Eat OpenParen
+
+	var init ast.Statement
+	switch {
+	case p.currentTokenIs(token.Semi):
+		// for (; ...
+	case p.currentTokenIs(token.Var) || p.currentTokenIs(token.Let) || p.currentTokenIs(token.Const):
+		declaration := p.current
+		if !p.expectPeek(token.Ident) {
+			return nil
+		}
+		name := &ast.Identifier{Token: p.current, Value: p.current.Value}
+		if p.peekIsForInKeyword() {
+			return p.parseForInStatement(forToken, declaration, name)
+		}
+
+		stmt := p.parseVariableValue(&ast.VariableStatement{Token: declaration, Name: name})
+		if stmt == nil {
+			return nil
+		}
+		init = stmt
+	case p.currentTokenIs(token.Ident) && p.peekIsForInKeyword():
+		name := &ast.Identifier{Token: p.current, Value: p.current.Value}
+		return p.parseForInStatement(forToken, nil, name)
+	default:
+		init = p.parseExpressionStatement()
+	}
+
+	if !p.currentTokenIs(token.Semi) {
+		p.error("expected %s, found %s", expected(token.Semi), describe(p.current))
+		return nil
+	}
+
+	stmt := &ast.ForStatement{Token: forToken, Init: init}
+	if !p.peekTokenIs(token.Semi) {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(ast.Lowest)
+	}
+	if !p.expectPeek(token.Semi) {
+		return nil
+	}
+
+	if !p.peekTokenIs(token.CloseParen) {
+		p.nextToken()
+		stmt.Update = p.parseExpression(ast.Lowest)
+	}
+	if !p.expectPeek(token.CloseParen) {
+		return nil
+	}
+
+	p.nextToken() // Eat CloseParen
+	stmt.Body = p.parseLoopBody()
+	return stmt
+}
+
+// peekIsForInKeyword reports whether the next token is in, or the contextual
+// keyword of.
+func (p *Parser) peekIsForInKeyword() bool {
+	return p.peekTokenIs(token.In) || (p.peekTokenIs(token.Ident) && p.next.Value == "of")
+}
+
+func (p *Parser) parseForInStatement(forToken, declaration *token.Token, name *ast.Identifier) ast.Statement {
+	stmt := &ast.ForInStatement{
+		Token:       forToken,
+		Declaration: declaration,
+		Name:        name,
+	}
+
+	p.nextToken() // in or of
+	stmt.Of = p.currentTokenIs(token.Ident)
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(ast.Lowest)
+	if !p.expectPeek(token.CloseParen) {
+		return nil
+	}
+
+	p.nextToken() // Eat CloseParen
+	stmt.Body = p.parseLoopBody()
+	return stmt
+}
@@ -12,7 +12,24 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 	for !p.peekTokenIs(token.CloseCurly) {
 		p.nextToken() // eat open curly
-		key := p.parseExpression(ast.Lowest)
+
+		if p.currentTokenIs(token.Ellipsis) {
+			spread := p.parseSpreadElement()
+			hash.Pairs[spread] = spread.Argument
+			hash.Keys = append(hash.Keys, spread)
+			if !p.peekTokenIs(token.CloseCurly) && !p.expectPeek(token.Comma) {
+				return nil
+			}
+			continue
+		}
+
+		// {name: value} uses the name itself as the key
+		var key ast.Expression
+		if p.currentIsPropertyName() && p.peekTokenIs(token.Colon) {
+			key = &ast.StringLiteral{Token: p.current, Value: p.current.Value}
+		} else {
+			key = p.parseExpression(ast.Lowest)
+		}
 		if !p.expectPeek(token.Colon) {
 			return nil
 		}
@@ -22,6 +39,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		value := p.parseExpression(ast.Lowest)
 
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 		if !p.peekTokenIs(token.CloseCurly) && !p.expectPeek(token.Comma) {
 			return nil
 		}
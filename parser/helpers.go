@@ -20,16 +20,31 @@ func (p *Parser) peekTokenIs(tokenType token.Type) bool {
 	return p.next.Type == tokenType
 }
 
+// peekOnNewLine reports whether the next token starts on a later line than
+// the current one ends.
+func (p *Parser) peekOnNewLine() bool {
+	return p.next.Start.Line > p.current.End.Line
+}
+
 func (p *Parser) expectPeek(tokenType token.Type) bool {
 	if p.peekTokenIs(tokenType) {
 		p.nextToken()
 		return true
 	}
+	p.peekError(expected(tokenType))
 	return false
 }
 
 func (p *Parser) nextToken() {
 	p.current = p.next
+	if p.current != nil {
+		switch {
+		case p.currentTokenIs(token.OpenCurly):
+			p.depth++
+		case p.currentTokenIs(token.CloseCurly) && p.depth > 0:
+			p.depth--
+		}
+	}
 	p.next = p.s.NextToken()
 }
 
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
+
+func (p *Parser) parseIfStatement() ast.Statement {
+	stmt := &ast.IfStatement{Token: p.current}
+
+	if !p.expectPeek(token.OpenParen) {
+		return nil
+	}
+	p.nextToken() // Eat OpenParen
+	stmt.Condition = p.parseExpression(ast.Lowest)
+	if !p.expectPeek(token.CloseParen) {
+		return nil
+	}
+
+	p.nextToken() // Eat CloseParen
+	stmt.Consequence = p.parseBody()
+
+	if p.peekTokenIs(token.Else) {
+		p.nextToken() // else
+		p.nextToken() // Eat Else
+
+		if p.currentTokenIs(token.If) {
+			stmt.Alternative = p.parseIfStatement()
+		} else {
+			stmt.Alternative = p.parseBody()
+		}
+	}
+	return stmt
+}
+
+// parseBody parses the body of a compound statement, which is either a block
+// or a single statement. A single statement is wrapped in a block so the
+// evaluator only has to deal with one shape.
+func (p *Parser) parseBody() *ast.BlockStatement {
+	if p.currentTokenIs(token.OpenCurly) {
+		return p.parseBlockStatement()
+	}
+
+	block := &ast.BlockStatement{Token: p.current}
+	if stmt := p.parseStatement(); stmt != nil {
+		block.Statements = append(block.Statements, stmt)
+	}
+	return block
+}
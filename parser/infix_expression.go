@@ -1,6 +1,9 @@
 package parser
 
-import "github.com/bundgaard/js/ast"
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
@@ -10,6 +13,10 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	// ** is right associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+	if p.currentTokenIs(token.Pow) {
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
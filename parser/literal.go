@@ -1,17 +1,86 @@
 package parser
 
 import (
+	"fmt"
 	"github.com/bundgaard/js/ast"
-	"log"
+	"math/big"
 	"strconv"
+	"strings"
 )
 
 func (p *Parser) parseNumberLiteral() ast.Expression {
-	n, err := strconv.Atoi(p.current.Value)
+	n, err := parseNumber(p.current.Value)
 	if err != nil {
-		log.Println("error number conversion", err)
+		p.error("%s", err)
 	}
-	return &ast.NumberLiteral{Token: p.current, Value: int64(n)}
+	return &ast.NumberLiteral{Token: p.current, Value: n}
+}
+
+// parseNumber converts a numeric literal as read by the scanner to its value.
+func parseNumber(literal string) (float64, error) {
+	base := 10
+	digits := literal
+	if len(literal) > 1 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 10 {
+			digits = literal[2:]
+		}
+	}
+
+	if !validSeparators(digits, base) {
+		return 0, fmt.Errorf("invalid numeric separator in %q", literal)
+	}
+	digits = strings.ReplaceAll(digits, "_", "")
+
+	if base != 10 {
+		if n, err := strconv.ParseUint(digits, base, 64); err == nil {
+			return float64(n), nil
+		}
+		// Literals beyond 64 bits still have a (rounded) double value.
+		n, ok := new(big.Int).SetString(digits, base)
+		if !ok {
+			return 0, fmt.Errorf("invalid number %q", literal)
+		}
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f, nil
+	}
+
+	f, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		// Out of range literals become Infinity or 0, as in JavaScript.
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return f, nil
+		}
+		return 0, fmt.Errorf("invalid number %q", literal)
+	}
+	return f, nil
+}
+
+// validSeparators reports whether every _ in digits sits between two digits.
+func validSeparators(digits string, base int) bool {
+	isDigit := func(c byte) bool {
+		if base == 16 {
+			return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+		}
+		return '0' <= c && c <= '9'
+	}
+
+	for i := 0; i < len(digits); i++ {
+		if digits[i] != '_' {
+			continue
+		}
+		if i == 0 || i == len(digits)-1 || !isDigit(digits[i-1]) || !isDigit(digits[i+1]) {
+			return false
+		}
+	}
+	return true
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
+
+func (p *Parser) parseLogicalExpression(left ast.Expression) ast.Expression {
+	expression := &ast.LogicalExpression{
+		Token:    p.current,
+		Operator: p.current.Value,
+		Left:     left,
+	}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+	return expression
+}
+
+// parseConditionalExpression parses test ? consequence : alternative. It is
+// right associative: a ? b : c ? d : e is a ? b : (c ? d : e).
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpression{Token: p.current, Condition: condition}
+
+	p.nextToken()
+	expression.Consequence = p.parseExpression(ast.Lowest)
+	if !p.expectPeek(token.Colon) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Alternative = p.parseExpression(ast.Conditional - 1)
+	return expression
+}
@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.current, Object: left}
+
+	// Keywords are valid property names: obj.for, obj.null
+	if !p.peekIsPropertyName() {
+		p.peekError("property name")
+		return nil
+	}
+	p.nextToken()
+	exp.Property = &ast.Identifier{Token: p.current, Value: p.current.Value}
+	return exp
+}
+
+func (p *Parser) peekIsPropertyName() bool {
+	if p.peekTokenIs(token.Ident) {
+		return true
+	}
+	t, ok := token.Keywords[p.next.Value]
+	return ok && t == p.next.Type
+}
+
+func (p *Parser) currentIsPropertyName() bool {
+	if p.currentTokenIs(token.Ident) {
+		return true
+	}
+	t, ok := token.Keywords[p.current.Value]
+	return ok && t == p.current.Type
+}
+
+func (p *Parser) parseThis() ast.Expression {
+	return &ast.ThisExpression{Token: p.current}
+}
@@ -0,0 +1,25 @@
+package parser
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
+
+// parseNewExpression parses new Callee(arguments). The callee stops before
+// the argument list, so new a.B(1) constructs a.B; the arguments are
+// optional, as in new Error.
+func (p *Parser) parseNewExpression() ast.Expression {
+	exp := &ast.NewExpression{Token: p.current}
+
+	p.nextToken() // Eat New
+	exp.Callee = p.parseExpression(ast.Call)
+	if exp.Callee == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.OpenParen) {
+		p.nextToken()
+		exp.Arguments = p.parseCallArguments()
+	}
+	return exp
+}
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
+
+// parseOptionalChain parses the link after ?., which is a property name, an
+// index in brackets or call arguments.
+func (p *Parser) parseOptionalChain(left ast.Expression) ast.Expression {
+	switch {
+	case p.peekTokenIs(token.OpenBracket):
+		p.nextToken()
+		exp := p.parseIndexExpression(left)
+		if exp == nil {
+			return nil
+		}
+		exp.(*ast.IndexExpression).Optional = true
+		return exp
+
+	case p.peekTokenIs(token.OpenParen):
+		p.nextToken()
+		exp := p.parseCallExpression(left)
+		exp.(*ast.CallExpression).Optional = true
+		return exp
+	}
+
+	exp := p.parseMemberExpression(left)
+	if exp == nil {
+		return nil
+	}
+	exp.(*ast.MemberExpression).Optional = true
+	return exp
+}
+
+// peekIsChainLink reports whether the next token continues a chain of
+// member, index and call expressions.
+func (p *Parser) peekIsChainLink() bool {
+	switch p.next.Type {
+	case token.Dot, token.OpenBracket, token.OpenParen, token.OptionalChain:
+		return true
+	}
+	return false
+}
+
+// hasOptionalLink reports whether the chain ending in exp has a ?. link.
+func hasOptionalLink(exp ast.Expression) bool {
+	for {
+		switch e := exp.(type) {
+		case *ast.MemberExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Object
+		case *ast.IndexExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Left
+		case *ast.CallExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Function
+		default:
+			return false
+		}
+	}
+}
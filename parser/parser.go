@@ -13,8 +13,28 @@ type (
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// maxNesting is how deeply statements and expressions may nest. Deeper input
+// is a syntax error rather than a stack overflow.
+const maxNesting = 1000
+
 type Parser struct {
-	s       *scanner.Scanner
+	s        *scanner.Scanner
+	filename string
+	errors   ErrorList
+	// failed is set once the statement being parsed has an error.
+	failed bool
+	// illegal holds the scanner's error message for each Illegal token.
+	illegal map[*token.Token]string
+	// depth is the number of braces opened and not yet closed, up to and
+	// including the current token.
+	depth int
+	// functions is the number of function bodies being parsed, and loops
+	// the number of loop bodies being parsed in the innermost of them.
+	functions int
+	loops     int
+	// nesting is the number of statements and expressions being parsed.
+	nesting int
+
 	current *token.Token
 	next    *token.Token
 
@@ -26,14 +46,27 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.current, Value: p.currentTokenIs(token.True)}
 }
 func New(rd io.RuneReader) *Parser {
+	return NewFile("", rd)
+}
+
+// NewFile returns a parser for a script read from rd. The filename is only
+// used in syntax errors.
+func NewFile(filename string, rd io.RuneReader) *Parser {
 	p := &Parser{
-		s: scanner.New(rd),
+		s:        scanner.New(rd),
+		filename: filename,
+	}
+	p.illegal = make(map[*token.Token]string)
+	p.s.Error = func(tok *token.Token, msg string) {
+		p.illegal[tok] = msg
 	}
 
 	p.infixParseFns = make(map[token.Type]infixParseFn)
 	p.prefixParseFns = make(map[token.Type]prefixParseFn)
 	p.registerPrefix(token.Ident, p.parseName)
 	p.registerPrefix(token.String, p.parseStringLiteral)
+	p.registerPrefix(token.Template, p.parseTemplateLiteral)
+	p.registerPrefix(token.TemplateHead, p.parseTemplateLiteral)
 	p.registerPrefix(token.Number, p.parseNumberLiteral)
 	p.registerPrefix(token.OpenCurly, p.parseHashLiteral)
 	p.registerPrefix(token.OpenBracket, p.parseArrayLiteral)
@@ -42,17 +75,49 @@ func New(rd io.RuneReader) *Parser {
 	p.registerPrefix(token.True, p.parseBoolean)
 	p.registerPrefix(token.False, p.parseBoolean)
 	p.registerPrefix(token.Null, p.parseNull)
-
-	p.registerPrefix(token.Dot, p.parseDotExpression)
+	p.registerPrefix(token.This, p.parseThis)
+	p.registerPrefix(token.Bang, p.parsePrefixExpression)
+	p.registerPrefix(token.Sub, p.parsePrefixExpression)
+	p.registerPrefix(token.Add, p.parsePrefixExpression)
+	p.registerPrefix(token.Typeof, p.parsePrefixExpression)
+	p.registerPrefix(token.NewKeyword, p.parseNewExpression)
 
 	p.registerInfix(token.Add, p.parseInfixExpression)
 	p.registerInfix(token.Mul, p.parseInfixExpression)
 	p.registerInfix(token.Div, p.parseInfixExpression)
 	p.registerInfix(token.Sub, p.parseInfixExpression)
+	p.registerInfix(token.Equal, p.parseInfixExpression)
+	p.registerInfix(token.NotEqual, p.parseInfixExpression)
+	p.registerInfix(token.StrictEqual, p.parseInfixExpression)
+	p.registerInfix(token.StrictNotEqual, p.parseInfixExpression)
+	p.registerInfix(token.Less, p.parseInfixExpression)
+	p.registerInfix(token.Greater, p.parseInfixExpression)
+	p.registerInfix(token.LessEqual, p.parseInfixExpression)
+	p.registerInfix(token.GreaterEqual, p.parseInfixExpression)
+
+	p.registerInfix(token.Mod, p.parseInfixExpression)
+	p.registerInfix(token.Pow, p.parseInfixExpression)
 
-	p.registerInfix(token.Assign, p.parseInfixExpression)
+	p.registerInfix(token.Assign, p.parseAssignExpression)
+	p.registerInfix(token.AddAssign, p.parseAssignExpression)
+	p.registerInfix(token.SubAssign, p.parseAssignExpression)
+	p.registerInfix(token.MulAssign, p.parseAssignExpression)
+	p.registerInfix(token.DivAssign, p.parseAssignExpression)
+	p.registerInfix(token.ModAssign, p.parseAssignExpression)
+	p.registerInfix(token.PowAssign, p.parseAssignExpression)
+	p.registerPrefix(token.Increment, p.parsePrefixUpdateExpression)
+	p.registerPrefix(token.Decrement, p.parsePrefixUpdateExpression)
+	p.registerInfix(token.Increment, p.parsePostfixUpdateExpression)
+	p.registerInfix(token.Decrement, p.parsePostfixUpdateExpression)
 	p.registerInfix(token.OpenBracket, p.parseIndexExpression)
+	p.registerInfix(token.Dot, p.parseMemberExpression)
 	p.registerInfix(token.OpenParen, p.parseCallExpression)
+	p.registerInfix(token.OptionalChain, p.parseOptionalChain)
+	p.registerInfix(token.And, p.parseLogicalExpression)
+	p.registerInfix(token.Or, p.parseLogicalExpression)
+	p.registerInfix(token.Nullish, p.parseLogicalExpression)
+	p.registerInfix(token.Question, p.parseConditionalExpression)
+	p.registerInfix(token.Arrow, p.parseArrowFunction)
 	p.nextToken()
 	p.nextToken()
 	return p
@@ -62,14 +127,20 @@ func NewString(data string) *Parser {
 	return New(strings.NewReader(data))
 }
 
+// Parse parses the whole script. Statements with syntax errors are left out
+// of the program and reported by Errors; parsing resumes at the next
+// statement.
 func (p *Parser) Parse() *ast.Program {
 
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
 	for p.current.Type != token.EOF {
+		p.failed = false
 		stmt := p.parseStatement()
-		if stmt != nil {
+		if p.failed {
+			p.synchronize()
+		} else if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
 
@@ -78,58 +149,152 @@ func (p *Parser) Parse() *ast.Program {
 	return program
 }
 
+// nest counts one more level of nesting, reporting an error when it would go
+// past maxNesting. Each successful call must be matched by unnest.
+func (p *Parser) nest() bool {
+	if p.nesting >= maxNesting {
+		p.error("nesting too deep")
+		return false
+	}
+	p.nesting++
+	return true
+}
+
+func (p *Parser) unnest() {
+	p.nesting--
+}
+
+// parseFunctionLiteral parses fn name(params) { body }. The name is
+// optional in a function expression.
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	fn := &ast.FunctionLiteral{Token: p.current}
-	// name
-	p.nextToken()
-	fn.Name = p.current.Value
+	if p.peekTokenIs(token.Ident) {
+		p.nextToken()
+		fn.Name = p.current.Value
+	}
 	if !p.expectPeek(token.OpenParen) {
 		return nil
 	}
-	fn.Parameters = p.parseFunctionArguments()
+	if !p.parseFunctionParameters(fn) {
+		return nil
+	}
 	if !p.expectPeek(token.OpenCurly) {
 		return nil
 	}
-	fn.Body = p.parseBlockStatement()
+	fn.Body = p.parseFunctionBody()
 	return fn
 }
 
-func (p *Parser) parseFunctionArguments() []*ast.Identifier {
-	var identifiers []*ast.Identifier
-
-	if p.peekTokenIs(token.CloseParen) {
-		p.nextToken()
+// parseFunctionBody parses the block of a function, where return may be
+// used. The loops around the function do not extend into it.
+func (p *Parser) parseFunctionBody() *ast.BlockStatement {
+	loops := p.loops
+	p.functions++
+	p.loops = 0
+	defer func() {
+		p.functions--
+		p.loops = loops
+	}()
+	return p.parseBlockStatement()
+}
 
-		return identifiers
-	}
-	p.nextToken() // eat OpenParen
+// parseLoopBody parses the body of a loop, where break and continue may be
+// used.
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loops++
+	defer func() { p.loops-- }()
+	return p.parseBody()
+}
 
-	ident := &ast.Identifier{Token: p.current, Value: p.current.Value}
-	identifiers = append(identifiers, ident)
-	for p.peekTokenIs(token.Comma) {
-		p.nextToken() // Eat Comma
+// parseFunctionParameters parses the parameter list after (: names with
+// optional defaults, a = 1, and a final ...rest parameter.
+func (p *Parser) parseFunctionParameters(fn *ast.FunctionLiteral) bool {
+	for !p.peekTokenIs(token.CloseParen) {
+		if p.peekTokenIs(token.Ellipsis) {
+			p.nextToken()
+			if !p.expectPeek(token.Ident) {
+				return false
+			}
+			fn.Rest = &ast.Identifier{Token: p.current, Value: p.current.Value}
+			break
+		}
 
-		p.nextToken() // become thing after comma
+		if !p.expectPeek(token.Ident) {
+			return false
+		}
+		param := &ast.Identifier{Token: p.current, Value: p.current.Value}
+		var value ast.Expression
+		if p.peekTokenIs(token.Assign) {
+			p.nextToken()
+			p.nextToken()
+			value = p.parseExpression(ast.Lowest)
+		}
+		addParameter(fn, param, value)
 
-		ident := &ast.Identifier{Token: p.current, Value: p.current.Value}
-		identifiers = append(identifiers, ident)
+		if !p.peekTokenIs(token.CloseParen) && !p.expectPeek(token.Comma) {
+			return false
+		}
 	}
+	return p.expectPeek(token.CloseParen)
+}
 
-	if !p.expectPeek(token.CloseParen) {
-		return nil
+// addParameter appends a parameter with an optional default value to fn.
+func addParameter(fn *ast.FunctionLiteral, param *ast.Identifier, value ast.Expression) {
+	if value != nil && fn.Defaults == nil {
+		fn.Defaults = make([]ast.Expression, len(fn.Parameters))
+	}
+	fn.Parameters = append(fn.Parameters, param)
+	if fn.Defaults != nil {
+		fn.Defaults = append(fn.Defaults, value)
 	}
-	return identifiers
 }
 
 func (p *Parser) parseNull() ast.Expression {
 	return &ast.Null{Value: "null", Token: p.current}
 }
 
+// groupedExpression parses a parenthesized expression, or the parameter
+// list of an arrow function when => follows the closing parenthesis.
 func (p *Parser) groupedExpression() ast.Expression {
+	if p.peekTokenIs(token.CloseParen) {
+		p.nextToken()
+		if !p.expectPeek(token.Arrow) {
+			return nil
+		}
+		return p.parseArrowBody(&ast.FunctionLiteral{Token: p.current, Arrow: true})
+	}
+
 	p.nextToken() // Skip (
-	expr := p.parseExpression(ast.Lowest)
+	expressions := []ast.Expression{p.parseListElement()}
+	var comma *token.Token
+	for p.peekTokenIs(token.Comma) {
+		p.nextToken()
+		if comma == nil {
+			comma = p.current
+		}
+		p.nextToken()
+		expressions = append(expressions, p.parseListElement())
+	}
 	if !p.expectPeek(token.CloseParen) {
 		return nil
 	}
-	return expr
+
+	if p.peekTokenIs(token.Arrow) {
+		p.nextToken()
+		fn := &ast.FunctionLiteral{Token: p.current, Arrow: true}
+		if !p.arrowParameters(fn, expressions) {
+			return nil
+		}
+		return p.parseArrowBody(fn)
+	}
+
+	if comma != nil {
+		p.errorAt(comma, "unexpected %s", describe(comma))
+		return nil
+	}
+	if spread, ok := expressions[0].(*ast.SpreadElement); ok {
+		p.errorAt(spread.Token, "unexpected %s", describe(spread.Token))
+		return nil
+	}
+	return expressions[0]
 }
@@ -2,8 +2,11 @@ package parser
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/bundgaard/js/ast"
 	"github.com/bundgaard/js/token"
+	"math"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -48,7 +51,7 @@ func TestParserNumberExpression(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	expect := `{"Statements":[{"Token":{"Type":17,"Value":"("},"Expression":{"Token":{"Type":15,"Value":"*"},"Left":{"Token":{"Type":13,"Value":"+"},"Left":{"Token":{"Type":26,"Value":"1"},"Value":1},"Operator":"+","Right":{"Token":{"Type":26,"Value":"2"},"Value":2}},"Operator":"*","Right":{"Token":{"Type":26,"Value":"100"},"Value":100}}}]}`
+	expect := `{"Statements":[{"Token":{"Type":17,"Value":"(","Start":{"Offset":0,"Line":1,"Column":1},"End":{"Offset":1,"Line":1,"Column":2}},"Expression":{"Token":{"Type":15,"Value":"*","Start":{"Offset":8,"Line":1,"Column":9},"End":{"Offset":9,"Line":1,"Column":10}},"Left":{"Token":{"Type":13,"Value":"+","Start":{"Offset":3,"Line":1,"Column":4},"End":{"Offset":4,"Line":1,"Column":5}},"Left":{"Token":{"Type":26,"Value":"1","Start":{"Offset":1,"Line":1,"Column":2},"End":{"Offset":2,"Line":1,"Column":3}},"Value":1},"Operator":"+","Right":{"Token":{"Type":26,"Value":"2","Start":{"Offset":5,"Line":1,"Column":6},"End":{"Offset":6,"Line":1,"Column":7}},"Value":2}},"Operator":"*","Right":{"Token":{"Type":26,"Value":"100","Start":{"Offset":10,"Line":1,"Column":11},"End":{"Offset":13,"Line":1,"Column":14}},"Value":100}}}]}`
 	if string(content) != expect {
 		t.Fail()
 	}
@@ -69,3 +72,440 @@ func helperDeepEqual(t *testing.T, expected, got *ast.Program) bool {
 	}
 	return result
 }
+
+func TestParserIfStatement(t *testing.T) {
+	p := NewString(`if (x < 10) { y; } else if (x == 10) { z; } else { w; }`)
+	program := p.Parse()
+	if len(program.Statements) != 1 {
+		t.Fatalf("expected 1 statement. got %d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.IfStatement)
+	if !ok {
+		t.Fatalf("expected *ast.IfStatement. got %T", program.Statements[0])
+	}
+	if stmt.Condition.String() != "(x < 10)" {
+		t.Errorf("expected condition %q. got %q", "(x < 10)", stmt.Condition)
+	}
+	if _, ok := stmt.Alternative.(*ast.IfStatement); !ok {
+		t.Errorf("expected else if. got %T", stmt.Alternative)
+	}
+	expected := "if ((x < 10)) {y} else if ((x == 10)) {z} else {w}"
+	if program.String() != expected {
+		t.Errorf("expected %q. got %q", expected, program.String())
+	}
+}
+
+func TestParserLoops(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`while (x < 10) { x; }`, "while ((x < 10)) {x}"},
+		{`for (var i = 0; i < 10; i) { break; }`, "for (var i = 0; (i < 10); i) {break;}"},
+		{`for (;;) { continue; }`, "for (; ; ) {continue;}"},
+		{`for (var x of xs) { x; }`, "for (var x of xs) {x}"},
+		{`for (k in obj) k;`, "for (k in obj) {k}"},
+	}
+
+	for idx, test := range tests {
+		program := NewString(test.Input).Parse()
+		if program.String() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, program.String())
+		}
+	}
+}
+
+func TestParserBlocks(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected []string
+	}{
+		{`{ 5 }`, []string{"*ast.BlockStatement"}},
+		{`{ let x = 2; } x`, []string{"*ast.BlockStatement", "*ast.ExpressionStatement"}},
+		{`{}`, []string{"*ast.BlockStatement"}},
+		{`let x;`, []string{"*ast.VariableStatement"}},
+		{`var k; for (k in [7, 8]) {} k`, []string{"*ast.VariableStatement", "*ast.ForInStatement", "*ast.ExpressionStatement"}},
+		{"var x = 1\nx\n{ x }", []string{"*ast.VariableStatement", "*ast.ExpressionStatement", "*ast.BlockStatement"}},
+		{"var c = 1\n++c", []string{"*ast.VariableStatement", "*ast.ExpressionStatement"}},
+		{"c\n--c\nc++", []string{"*ast.ExpressionStatement", "*ast.ExpressionStatement", "*ast.ExpressionStatement"}},
+	}
+
+	for idx, test := range tests {
+		p := NewString(test.Input)
+		program := p.Parse()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("test[%04d] %q: %v", idx, test.Input, p.Errors())
+		}
+		var got []string
+		for _, stmt := range program.Statements {
+			got = append(got, fmt.Sprintf("%T", stmt))
+		}
+		if !reflect.DeepEqual(got, test.Expected) {
+			t.Errorf("test[%04d] expected %v. got %v", idx, test.Expected, got)
+		}
+	}
+}
+
+func TestParserAssignment(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`x = 1;`, "(x = 1)"},
+		{`a = b = c;`, "(a = (b = c))"},
+		{`x += 1 + 2;`, "(x += (1 + 2))"},
+		{`xs[0] *= 2;`, "((xs[0]) *= 2)"},
+		{`i++;`, "(i++)"},
+		{`--i;`, "(--i)"},
+		{`a = i++ + 1;`, "(a = ((i++) + 1))"},
+	}
+
+	for idx, test := range tests {
+		program := NewString(test.Input).Parse()
+		if program.String() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, program.String())
+		}
+	}
+}
+
+func TestParserMemberExpression(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`a.b;`, "(a.b)"},
+		{`a.b.c(1);`, "((a.b).c)(1)"},
+		{`a.b = 2;`, "((a.b) = 2)"},
+		{`a.b[0].c;`, "(((a.b)[0]).c)"},
+		{`this.x;`, "(this.x)"},
+		{`obj.for;`, "(obj.for)"},
+	}
+
+	for idx, test := range tests {
+		program := NewString(test.Input).Parse()
+		if program.String() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, program.String())
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		Literal  string
+		Expected float64
+		Valid    bool
+	}{
+		{"1_000", 1000, true},
+		{"0xff_ff", 65535, true},
+		{"1e400", math.Inf(1), true},
+		{"0x1_0000_0000_0000_0000", 18446744073709551616, true},
+		{"1__0", 0, false},
+		{"1_", 0, false},
+		{"1_.5", 0, false},
+		{"1e", 0, false},
+		{"0b102", 0, false},
+	}
+
+	for idx, test := range tests {
+		got, err := parseNumber(test.Literal)
+		if (err == nil) != test.Valid {
+			t.Errorf("test[%04d] %q: unexpected error state %v", idx, test.Literal, err)
+			continue
+		}
+		if test.Valid && got != test.Expected {
+			t.Errorf("test[%04d] %q: expected %v. got %v", idx, test.Literal, test.Expected, got)
+		}
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected []string
+	}{
+		{"var = 1;", []string{`1:5: expected name, found "="`}},
+		{"1 = 2;", []string{"1:3: invalid assignment target"}},
+		{"if (x { }", []string{`1:7: expected ")", found "{"`}},
+		{"a.;", []string{`1:3: expected property name, found ";"`}},
+		{"var a = 1 @ 2;", []string{`1:11: unexpected character '@'`}},
+		{"x = 1_;", []string{`1:5: invalid numeric separator in "1_"`}},
+		{"/* never closed", []string{"1:1: unterminated comment"}},
+		{"fn f() {\n  return 1;\n", []string{"3:1: expected \"}\", found end of input"}},
+		{"return 1;", []string{"1:1: illegal return statement outside of function"}},
+		{"break;", []string{"1:1: illegal break statement"}},
+		{"if (true) { continue; }", []string{"1:13: illegal continue statement"}},
+		{"while (true) { fn f() { break; } }", []string{"1:25: illegal break statement"}},
+		{"for (;;) { while (x) { break; } continue; }\nbreak;", []string{"2:1: illegal break statement"}},
+		{"if (false) { return 1; } 5", []string{"1:14: illegal return statement outside of function"}},
+		{"fn f() { var g = () => { return 1; }; return g; }\nreturn 2;", []string{"2:1: illegal return statement outside of function"}},
+		{"const z;", []string{"1:8: missing initializer in const declaration"}},
+		{"1 2", []string{`1:3: unexpected "2"`}},
+		{"0.1.2", []string{`1:4: unexpected ".2"`}},
+		{"var x = 1 var y = 2", []string{`1:11: unexpected "var"`}},
+		{"fn f() { return 1 2 }", []string{`1:19: unexpected "2"`}},
+		{"while (x) { break 2 }", []string{`1:19: unexpected "2"`}},
+		{"if (x) {\n  a = (;\n  b = 1;\n}\nvar c = ];", []string{
+			`2:8: unexpected ";"`,
+			`5:9: unexpected "]"`,
+		}},
+		{"var a = (;\nvar b = 2;\nvar c = ];\nvar d = 4;", []string{
+			`1:10: unexpected ";"`,
+			`3:9: unexpected "]"`,
+		}},
+	}
+
+	for idx, test := range tests {
+		p := NewString(test.Input)
+		p.Parse()
+
+		errors := p.Errors()
+		if len(errors) != len(test.Expected) {
+			t.Errorf("test[%04d] %q: expected %d errors. got %v", idx, test.Input, len(test.Expected), errors)
+			continue
+		}
+		for i, err := range errors {
+			if err.Error() != test.Expected[i] {
+				t.Errorf("test[%04d] %q: expected %q. got %q", idx, test.Input, test.Expected[i], err.Error())
+			}
+		}
+	}
+}
+
+func TestParserErrorsRecover(t *testing.T) {
+	p := NewFile("script.js", strings.NewReader("var a = (;\nvar b = 2;\n)\nvar c = b;"))
+	program := p.Parse()
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected the 2 valid statements. got %d: %s", len(program.Statements), program)
+	}
+
+	errors := p.Errors()
+	if len(errors) != 2 {
+		t.Fatalf("expected 2 errors. got %v", errors)
+	}
+	err := errors[1]
+	if err.File != "script.js" || err.Line != 3 || err.Column != 1 {
+		t.Errorf("expected error at script.js:3:1. got %s:%d:%d", err.File, err.Line, err.Column)
+	}
+	if err.Token.Type != token.CloseParen {
+		t.Errorf("expected offending token %s. got %s", token.CloseParen, err.Token.Type)
+	}
+	if errors.Error() != `script.js:1:10: unexpected ";" (and 1 more errors)` {
+		t.Errorf("unexpected error list message %q", errors.Error())
+	}
+}
+
+func TestParserManyErrors(t *testing.T) {
+	// The parser used to give up and exit the process after ten errors.
+	p := NewString(strings.Repeat(")\n", 50))
+	p.Parse()
+	if len(p.Errors()) != 50 {
+		t.Errorf("expected 50 errors. got %d", len(p.Errors()))
+	}
+	if NewString("var a = 1;").Errors().Err() != nil {
+		t.Errorf("expected no error for a valid script")
+	}
+}
+
+func TestParserNesting(t *testing.T) {
+	deep := []string{
+		strings.Repeat("[", 1000000),
+		strings.Repeat("{", 1000000),
+		strings.Repeat("-", 1000000) + "1",
+		strings.Repeat("(", 5000) + "1" + strings.Repeat(")", 5000),
+	}
+	for idx, input := range deep {
+		p := NewString(input)
+		p.Parse()
+		errs := p.Errors()
+		if len(errs) == 0 || errs[0].Message != "nesting too deep" {
+			t.Errorf("test[%04d] expected a nesting error. got %v", idx, errs)
+		}
+	}
+
+	p := NewString(strings.Repeat("[", 300) + strings.Repeat("]", 300))
+	p.Parse()
+	if err := p.Errors().Err(); err != nil {
+		t.Errorf("expected no error for 300 nested arrays. got %v", err)
+	}
+}
+
+func TestParserTemplateLiteral(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"`plain`;", "`plain`"},
+		{"`a ${x + 1} b`;", "`a ${(x + 1)} b`"},
+		{"`${a}${b}`;", "`${a}${b}`"},
+		{"`outer ${`inner ${x}`}`;", "`outer ${`inner ${x}`}`"},
+		{"`${ {a: 1}.a }`;", "`${({a:1}.a)}`"},
+	}
+
+	for idx, test := range tests {
+		p := NewString(test.Input)
+		program := p.Parse()
+		if len(p.Errors()) > 0 {
+			t.Errorf("test[%04d] unexpected errors %v", idx, p.Errors())
+			continue
+		}
+		if program.String() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, program.String())
+		}
+	}
+
+	p := NewString("`a ${} b`;")
+	p.Parse()
+	if len(p.Errors()) != 1 || p.Errors()[0].Message != `expected expression in template substitution, found " b"` {
+		t.Errorf("expected an error for the empty substitution. got %v", p.Errors())
+	}
+}
+
+func TestParserOperators(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`-5;`, "(-5)"},
+		{`!a == b;`, "((!a) == b)"},
+		{`-a * b;`, "((-a) * b)"},
+		{`typeof x === "string";`, "((typeof x) === string)"},
+		{`a || b && c;`, "(a || (b && c))"},
+		{`a && b || c;`, "((a && b) || c)"},
+		{`a ?? b == c;`, "(a ?? (b == c))"},
+		{`a ? b : c;`, "(a ? b : c)"},
+		{`a ? b : c ? d : e;`, "(a ? b : (c ? d : e))"},
+		{`x = a || b ? c + 1 : d;`, "(x = ((a || b) ? (c + 1) : d))"},
+		{`a?.b.c;`, "((a?.b).c)"},
+		{`a?.[0]?.(1);`, "(a?.[0])?.(1)"},
+		{`a?.b + 1;`, "((a?.b) + 1)"},
+	}
+
+	for idx, test := range tests {
+		p := NewString(test.Input)
+		program := p.Parse()
+		if len(p.Errors()) > 0 {
+			t.Errorf("test[%04d] unexpected errors %v", idx, p.Errors())
+			continue
+		}
+		if program.String() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, program.String())
+		}
+	}
+
+	p := NewString("a?.b = 1;")
+	p.Parse()
+	if len(p.Errors()) != 1 {
+		t.Errorf("expected an error assigning to an optional chain. got %v", p.Errors())
+	}
+}
+
+func TestParserFunctions(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`var f = fn(a) { return a; };`, "var f = fn(a) return a;"},
+		{`var f = function(a, b) { return a + b; };`, "var f = function(a, b) return (a + b);"},
+		{`function add(a, b) { return a + b; }`, "function add (a, b) return (a + b);"},
+		{`var f = (a, b) => a + b;`, "var f = (a, b) => return (a + b);"},
+		{`var f = x => x * 2;`, "var f = (x) => return (x * 2);"},
+		{`var f = () => { return 1; };`, "var f = () => return 1;"},
+		{`var f = a => b => a + b;`, "var f = (a) => return (b) => return (a + b);;"},
+		{`var x = (1 + 2) * 3;`, "var x = ((1 + 2) * 3)"},
+	}
+
+	for idx, test := range tests {
+		p := NewString(test.Input)
+		program := p.Parse()
+		if len(p.Errors()) > 0 {
+			t.Errorf("test[%04d] unexpected errors %v", idx, p.Errors())
+			continue
+		}
+		if program.String() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, program.String())
+		}
+	}
+
+	if _, ok := NewString(`function add(a, b) { return a + b; }`).Parse().Statements[0].(*ast.FunctionDeclaration); !ok {
+		t.Errorf("expected a named function statement to be a declaration")
+	}
+
+	for _, input := range []string{`(a + 1) => a;`, `(1, 2);`, `fn (1) {}`} {
+		p := NewString(input)
+		p.Parse()
+		if len(p.Errors()) != 1 {
+			t.Errorf("%s: expected one error. got %v", input, p.Errors())
+		}
+	}
+}
+
+func TestParserParametersAndSpread(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`var f = fn(a, b = 2) { return b; };`, "var f = fn(a, b = 2) return b;"},
+		{`var f = fn(a, ...rest) { return rest; };`, "var f = fn(a, ...rest) return rest;"},
+		{`var f = (a = 1, ...rest) => a;`, "var f = (a = 1, ...rest) => return a;"},
+		{`f(...xs, 1);`, "f(...xs, 1)"},
+		{`var a = [0, ...xs];`, "var a = [0, ...xs]"},
+	}
+
+	for idx, test := range tests {
+		p := NewString(test.Input)
+		program := p.Parse()
+		if len(p.Errors()) > 0 {
+			t.Errorf("test[%04d] unexpected errors %v", idx, p.Errors())
+			continue
+		}
+		if program.String() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, program.String())
+		}
+	}
+
+	for _, input := range []string{`fn (...a, b) {}`, `(...a, b) => a;`, `(...a);`, `fn (a = ) {}`} {
+		p := NewString(input)
+		p.Parse()
+		if len(p.Errors()) != 1 {
+			t.Errorf("%s: expected one error. got %v", input, p.Errors())
+		}
+	}
+}
+
+func TestParserExceptions(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`throw 1;`, "throw 1;"},
+		{`throw new Error("x");`, `throw new Error(x);`},
+		{`try { a(); } catch (e) { b(); }`, "try {a()} catch (e) {b()}"},
+		{`try { a(); } catch { b(); }`, "try {a()} catch {b()}"},
+		{`try { a(); } finally { b(); }`, "try {a()} finally {b()}"},
+		{`try { a(); } catch (e) { b(); } finally { c(); }`, "try {a()} catch (e) {b()} finally {c()}"},
+		{`var p = new Point;`, "var p = new Point()"},
+		{`var p = new a.Point(1, 2).x;`, "var p = (new (a.Point)(1, 2).x)"},
+	}
+
+	for idx, test := range tests {
+		p := NewString(test.Input)
+		program := p.Parse()
+		if len(p.Errors()) > 0 {
+			t.Errorf("test[%04d] unexpected errors %v", idx, p.Errors())
+			continue
+		}
+		if program.String() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, program.String())
+		}
+	}
+
+	for _, input := range []string{`throw;`, `try { a(); }`, `try { a(); } catch (1) {}`, `try a();`} {
+		p := NewString(input)
+		p.Parse()
+		if len(p.Errors()) != 1 {
+			t.Errorf("%s: expected one error. got %v", input, p.Errors())
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package parser
+
+import "github.com/bundgaard/js/ast"
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    p.current,
+		Operator: p.current.Value,
+	}
+
+	p.nextToken()
+	expression.Right = p.parseExpression(ast.Prefix)
+	return expression
+}
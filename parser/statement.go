@@ -6,9 +6,57 @@ import (
 )
 
 func (p *Parser) parseStatement() ast.Statement {
+	if !p.nest() {
+		return nil
+	}
+	defer p.unnest()
+
 	switch p.current.Type {
-	case token.Var:
+	case token.Var, token.Let, token.Const:
 		return p.parseVariable()
+	case token.OpenCurly:
+		// At the start of a statement { opens a block, not an object.
+		return p.parseBlockStatement()
+	case token.If:
+		return p.parseIfStatement()
+	case token.Return:
+		return p.parseReturnStatement()
+	case token.While:
+		return p.parseWhileStatement()
+	case token.For:
+		return p.parseForStatement()
+	case token.Throw:
+		return p.parseThrowStatement()
+	case token.Try:
+		return p.parseTryStatement()
+	case token.Function:
+		if p.peekTokenIs(token.Ident) {
+			return p.parseFunctionDeclaration()
+		}
+		return p.parseExpressionStatement()
+	case token.Break:
+		stmt := &ast.BreakStatement{Token: p.current}
+		if p.loops == 0 {
+			p.error("illegal break statement")
+			return nil
+		}
+		if !p.endStatement() {
+			return nil
+		}
+		return stmt
+	case token.Continue:
+		stmt := &ast.ContinueStatement{Token: p.current}
+		if p.loops == 0 {
+			p.error("illegal continue statement")
+			return nil
+		}
+		if !p.endStatement() {
+			return nil
+		}
+		return stmt
+	case token.Semi:
+		// empty statement
+		return nil
 	case token.CommentLine:
 		return nil
 	case token.CommentBlock:
@@ -24,24 +72,58 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	// foo[0] = 1300
 	stmt := &ast.ExpressionStatement{Token: p.current}
 	stmt.Expression = p.parseExpression(ast.Lowest)
-	// dot expression
-	// foo.bar
-	if p.peekTokenIs(token.Dot) {
+
+	if !p.endStatement() {
+		return nil
+	}
+	return stmt
+}
+
+// endStatement consumes the semicolon that ends a statement. Without one the
+// statement must be followed by a closing brace, the end of input or a line
+// break; anything else is a syntax error.
+func (p *Parser) endStatement() bool {
+	switch {
+	case p.peekTokenIs(token.Semi):
 		p.nextToken()
-		right := p.parseInfixExpression(stmt.Expression)
-		stmt.Expression = right
+	case p.peekTokenIs(token.CloseCurly), p.peekTokenIs(token.EOF):
+	case p.peekOnNewLine():
+	default:
+		p.errorAt(p.next, "unexpected %s", describe(p.next))
+		return false
 	}
+	return true
+}
 
-	if p.peekTokenIs(token.Assign) {
+func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+	stmt := &ast.ReturnStatement{Token: p.current}
+	if p.functions == 0 {
+		p.error("illegal return statement outside of function")
+		return nil
+	}
 
-		p.nextToken()
+	// return;, return } and return at the end of a line have no value
+	if p.peekTokenIs(token.Semi) || p.peekTokenIs(token.CloseCurly) || p.peekTokenIs(token.EOF) || p.peekOnNewLine() {
+		if p.peekTokenIs(token.Semi) {
+			p.nextToken()
+		}
+		return stmt
+	}
 
-		right := p.parseInfixExpression(stmt.Expression)
-		stmt.Expression = right
+	p.nextToken() // Eat Return
+	stmt.ReturnValue = p.parseExpression(ast.Lowest)
+	if !p.endStatement() {
+		return nil
 	}
+	return stmt
+}
 
-	if p.peekTokenIs(token.Semi) {
-		p.nextToken()
+func (p *Parser) parseFunctionDeclaration() ast.Statement {
+	stmt := &ast.FunctionDeclaration{Token: p.current}
+	fn, ok := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+	if !ok {
+		return nil
 	}
+	stmt.Function = fn
 	return stmt
 }
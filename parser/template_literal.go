@@ -0,0 +1,33 @@
+package parser
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
+
+// parseTemplateLiteral parses a template from its first text token. The
+// scanner splits a template into text tokens around the ${} substitutions:
+// a Template without substitutions, or a TemplateHead, TemplateMiddles and
+// a TemplateTail.
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	template := &ast.TemplateLiteral{Token: p.current}
+	for {
+		template.Strings = append(template.Strings, p.current.Value)
+		if p.currentTokenIs(token.Template) || p.currentTokenIs(token.TemplateTail) {
+			return template
+		}
+
+		if p.peekTokenIs(token.TemplateMiddle) || p.peekTokenIs(token.TemplateTail) {
+			p.peekError("expression in template substitution")
+			return nil
+		}
+		p.nextToken()
+		template.Expressions = append(template.Expressions, p.parseExpression(ast.Lowest))
+
+		if !p.peekTokenIs(token.TemplateMiddle) && !p.peekTokenIs(token.TemplateTail) {
+			p.peekError(`"}"`)
+			return nil
+		}
+		p.nextToken()
+	}
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
+
+func (p *Parser) parseThrowStatement() ast.Statement {
+	stmt := &ast.ThrowStatement{Token: p.current}
+
+	if p.peekTokenIs(token.Semi) || p.peekTokenIs(token.CloseCurly) || p.peekTokenIs(token.EOF) {
+		p.peekError("expression")
+		return nil
+	}
+
+	p.nextToken() // Eat Throw
+	stmt.Argument = p.parseExpression(ast.Lowest)
+	if stmt.Argument == nil || !p.endStatement() {
+		return nil
+	}
+	return stmt
+}
+
+func (p *Parser) parseTryStatement() ast.Statement {
+	stmt := &ast.TryStatement{Token: p.current}
+
+	if !p.expectPeek(token.OpenCurly) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.Catch) {
+		p.nextToken() // Eat CloseCurly
+		if p.peekTokenIs(token.OpenParen) {
+			p.nextToken()
+			if !p.expectPeek(token.Ident) {
+				return nil
+			}
+			stmt.Param = &ast.Identifier{Token: p.current, Value: p.current.Value}
+			if !p.expectPeek(token.CloseParen) {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.OpenCurly) {
+			return nil
+		}
+		stmt.Handler = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.Finally) {
+		p.nextToken() // Eat CloseCurly
+		if !p.expectPeek(token.OpenCurly) {
+			return nil
+		}
+		stmt.Finalizer = p.parseBlockStatement()
+	}
+
+	if stmt.Handler == nil && stmt.Finalizer == nil {
+		p.peekError(`"catch" or "finally"`)
+		return nil
+	}
+	return stmt
+}
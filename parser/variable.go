@@ -11,13 +11,22 @@ func (p *Parser) parseVariable() *ast.VariableStatement {
 		return nil
 	}
 	stmt.Name = &ast.Identifier{Token: p.current, Value: p.current.Value}
-	if !p.expectPeek(token.Assign) {
+	return p.parseVariableValue(stmt)
+}
+
+// parseVariableValue parses the = value part of a declaration whose name has
+// already been consumed. Only const requires a value.
+func (p *Parser) parseVariableValue(stmt *ast.VariableStatement) *ast.VariableStatement {
+	if p.peekTokenIs(token.Assign) {
+		p.nextToken()
+		p.nextToken()
+		stmt.Value = p.parseExpression(ast.Lowest)
+	} else if stmt.Token.Type == token.Const {
+		p.errorAt(p.next, "missing initializer in const declaration")
 		return nil
 	}
-	p.nextToken()
-	stmt.Value = p.parseExpression(ast.Lowest)
-	if p.peekTokenIs(token.Semi) {
-		p.nextToken()
+	if !p.endStatement() {
+		return nil
 	}
 	return stmt
 }
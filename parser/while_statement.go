@@ -0,0 +1,23 @@
+package parser
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/token"
+)
+
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.current}
+
+	if !p.expectPeek(token.OpenParen) {
+		return nil
+	}
+	p.nextToken() // Eat OpenParen
+	stmt.Condition = p.parseExpression(ast.Lowest)
+	if !p.expectPeek(token.CloseParen) {
+		return nil
+	}
+
+	p.nextToken() // Eat CloseParen
+	stmt.Body = p.parseLoopBody()
+	return stmt
+}
@@ -0,0 +1,203 @@
+package js
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/bundgaard/js/code"
+	"github.com/bundgaard/js/compiler"
+	"github.com/bundgaard/js/object"
+	"github.com/bundgaard/js/parser"
+	"github.com/bundgaard/js/vm"
+	"strings"
+)
+
+// Program is a compiled script. It is immutable, so one Program may be run
+// any number of times, from any number of goroutines at once, each run in
+// an environment of its own.
+type Program struct {
+	main *object.CompiledFunction
+}
+
+// Compile parses and compiles source. Syntax errors are returned as a
+// parser.ErrorList and programs the compiler rejects as a *compiler.Error.
+func Compile(source string) (*Program, error) {
+	p := parser.New(strings.NewReader(source))
+	program := p.Parse()
+	if err := p.Errors().Err(); err != nil {
+		return nil, err
+	}
+
+	main, err := compiler.Compile(program)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{main: main}, nil
+}
+
+// Run runs the program in environment, or in a new environment when
+// environment is nil. Errors are reported as by the package-level Run.
+// Runs that share an environment must not overlap, unless it is from
+// object.NewSharedEnvironment, in which any number may run at once.
+func (p *Program) Run(environment *object.Environment) (object.Object, error) {
+	if environment == nil {
+		environment = object.NewEnvironment()
+	}
+	return vm.Run(p.main, environment)
+}
+
+// RunProgram runs p in the runtime's global scope.
+func (r *Runtime) RunProgram(p *Program) (object.Object, error) {
+	return r.RunProgramContext(context.Background(), p)
+}
+
+// RunProgramContext is RunProgram ending with an error when ctx is done.
+func (r *Runtime) RunProgramContext(ctx context.Context, p *Program) (object.Object, error) {
+	return p.Run(r.begin(ctx))
+}
+
+// programMagic starts every serialized program. Its last byte is the
+// version of the format, which changes with the bytecode.
+const programMagic = "jsbc\x02"
+
+// ErrProgramFormat is returned by UnmarshalBinary for data that is not a
+// program serialized by this version of the package.
+var ErrProgramFormat = errors.New("not a compiled program or compiled by another version")
+
+// MarshalBinary serializes the compiled program, so it can be stored and
+// loaded with UnmarshalBinary without parsing the source again.
+func (p *Program) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(programMagic)
+	if err := gob.NewEncoder(&buf).Encode(encodeFunction(p.main)); err != nil {
+		return nil, fmt.Errorf("encode program: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary loads a program serialized by MarshalBinary. The bytecode
+// is not verified; a damaged program may fail with an *eval.PanicError when
+// it runs, so load programs only from sources you trust.
+func (p *Program) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(programMagic)) {
+		return ErrProgramFormat
+	}
+	var fn function
+	if err := gob.NewDecoder(bytes.NewReader(data[len(programMagic):])).Decode(&fn); err != nil {
+		return fmt.Errorf("decode program: %w", err)
+	}
+	main, err := fn.decode()
+	if err != nil {
+		return err
+	}
+	p.main = main
+	return nil
+}
+
+// LoadProgram loads a program serialized by MarshalBinary.
+func LoadProgram(data []byte) (*Program, error) {
+	p := &Program{}
+	if err := p.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// function is the serialized form of an object.CompiledFunction.
+type function struct {
+	Name          string
+	Parameters    []string
+	Bound         int
+	Rest          string
+	Arrow         bool
+	UsesArguments bool
+	UsesThis      bool
+	Source        string
+	Instructions  []byte
+	Constants     []constant
+	Positions     code.Positions
+	Calls         code.Positions
+}
+
+// constant is a serialized constant. Kind tells which of the other fields
+// holds its value.
+type constant struct {
+	Kind     constantKind
+	Number   float64
+	String   string
+	Function *function
+}
+
+type constantKind byte
+
+// Constant kinds start at 1, since gob leaves out zero values.
+const (
+	numberConstant constantKind = iota + 1
+	stringConstant
+	functionConstant
+)
+
+func encodeFunction(fn *object.CompiledFunction) *function {
+	f := &function{
+		Name:          fn.Name,
+		Parameters:    fn.Parameters,
+		Bound:         fn.Bound,
+		Rest:          fn.Rest,
+		Arrow:         fn.Arrow,
+		UsesArguments: fn.UsesArguments,
+		UsesThis:      fn.UsesThis,
+		Source:        fn.Source,
+		Instructions:  fn.Instructions,
+		Positions:     fn.Positions,
+		Calls:         fn.Calls,
+	}
+	for _, c := range fn.Constants {
+		switch c := c.(type) {
+		case *object.NumberObject:
+			f.Constants = append(f.Constants, constant{Kind: numberConstant, Number: c.Value})
+		case *object.StringObject:
+			f.Constants = append(f.Constants, constant{Kind: stringConstant, String: c.Value})
+		case *object.CompiledFunction:
+			f.Constants = append(f.Constants, constant{Kind: functionConstant, Function: encodeFunction(c)})
+		default:
+			// The compiler only creates the constants above.
+			panic(fmt.Sprintf("cannot encode constant %T", c))
+		}
+	}
+	return f
+}
+
+func (f *function) decode() (*object.CompiledFunction, error) {
+	fn := &object.CompiledFunction{
+		Name:          f.Name,
+		Parameters:    f.Parameters,
+		Bound:         f.Bound,
+		Rest:          f.Rest,
+		Arrow:         f.Arrow,
+		UsesArguments: f.UsesArguments,
+		UsesThis:      f.UsesThis,
+		Source:        f.Source,
+		Instructions:  f.Instructions,
+		Positions:     f.Positions,
+		Calls:         f.Calls,
+	}
+	for _, c := range f.Constants {
+		switch {
+		case c.Kind == numberConstant:
+			fn.Constants = append(fn.Constants, &object.NumberObject{Value: c.Number})
+		case c.Kind == stringConstant:
+			fn.Constants = append(fn.Constants, &object.StringObject{Value: c.String})
+		case c.Kind == functionConstant && c.Function != nil:
+			inner, err := c.Function.decode()
+			if err != nil {
+				return nil, err
+			}
+			fn.Constants = append(fn.Constants, inner)
+		default:
+			return nil, ErrProgramFormat
+		}
+	}
+	return fn, nil
+}
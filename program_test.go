@@ -0,0 +1,153 @@
+package js
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/bundgaard/js/compiler"
+	"github.com/bundgaard/js/object"
+	"github.com/bundgaard/js/parser"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const programSource = `
+function fib(n) {
+	if (n < 2) {
+		return n;
+	}
+	return fib(n - 1) + fib(n - 2);
+}
+var greeting = ` + "`hello ${name}`" + `;
+[fib(n), greeting];
+`
+
+func TestCompileProgram(t *testing.T) {
+	program, err := Compile(programSource)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, tt := range []struct {
+		n        float64
+		name     string
+		expected string
+	}{
+		{10, "a", "[55, hello a]"},
+		{15, "b", "[610, hello b]"},
+	} {
+		environment := object.NewEnvironment()
+		environment.Set("n", &object.NumberObject{Value: tt.n})
+		environment.Set("name", &object.StringObject{Value: tt.name})
+		result, err := program.Run(environment)
+		if err != nil || result.Inspect() != tt.expected {
+			t.Errorf("expected %s. got %v, %v", tt.expected, result, err)
+		}
+		if greeting, _ := environment.Get("greeting"); greeting.Inspect() != "hello "+tt.name {
+			t.Errorf("expected the run to declare greeting. got %v", greeting)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	if _, err := Compile("var x = ;"); err == nil {
+		t.Errorf("expected a syntax error")
+	} else if _, ok := err.(parser.ErrorList); !ok {
+		t.Errorf("expected a parser.ErrorList. got %T %v", err, err)
+	}
+	if _, err := Compile("f(" + strings.Repeat("0, ", 256) + "0);"); err == nil {
+		t.Errorf("expected a compile error")
+	} else if _, ok := err.(*compiler.Error); !ok {
+		t.Errorf("expected a *compiler.Error. got %T %v", err, err)
+	}
+
+	program, err := Compile("function f() { null.x; }\nf();")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, err = program.Run(nil)
+	if err == nil || err.Error() != "1:20: TypeError: cannot read property \"x\" of null" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestProgramConcurrentRuns(t *testing.T) {
+	program, err := Compile(programSource)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			environment := object.NewEnvironment()
+			environment.Set("n", &object.NumberObject{Value: 12})
+			environment.Set("name", &object.StringObject{Value: fmt.Sprint(i)})
+			result, err := program.Run(environment)
+			if expected := fmt.Sprintf("[144, hello %d]", i); err != nil || result.Inspect() != expected {
+				errs <- fmt.Errorf("expected %s. got %v, %v", expected, result, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestProgramMarshalBinary(t *testing.T) {
+	program, err := Compile(programSource + `
+var o = {count: 0, inc: function() { this.count += 1; return this; }};
+o.inc().inc().count;`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	data, err := program.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	loaded, err := LoadProgram(data)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	environment := object.NewEnvironment()
+	environment.Set("n", &object.NumberObject{Value: 0})
+	environment.Set("name", &object.StringObject{Value: ""})
+	result, err := loaded.Run(environment)
+	if err != nil || result.Inspect() != "2" {
+		t.Errorf("expected 2. got %v, %v", result, err)
+	}
+
+	again, err := loaded.MarshalBinary()
+	if err != nil || !bytes.Equal(data, again) {
+		t.Errorf("expected the loaded program to serialize the same. got %v", err)
+	}
+
+	for _, data := range [][]byte{nil, []byte("var x = 1;"), data[:len(data)/2]} {
+		if _, err := LoadProgram(data); err == nil {
+			t.Errorf("expected an error loading %q", data)
+		}
+	}
+}
+
+func TestRuntimeRunProgram(t *testing.T) {
+	r := NewRuntime()
+	program, err := Compile(`count = count + 1;`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	r.Set("count", 0)
+	for i := 0; i < 3; i++ {
+		if _, err := r.RunProgram(program); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if count, _ := r.Get("count"); count.Inspect() != "3" {
+		t.Errorf("expected count to be 3. got %v", count)
+	}
+}
@@ -0,0 +1,136 @@
+package js
+
+import (
+	"context"
+	"fmt"
+	"github.com/bundgaard/js/eval"
+	"github.com/bundgaard/js/object"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Runtime is an interpreter instance. It owns the global scope its scripts
+// run in, so variables declared by one script are seen by the next, and
+// values and functions the embedder adds are visible only to this runtime.
+// Runtimes share no state; a process may create as many as it needs, and
+// use each from a goroutine of its own.
+type Runtime struct {
+	globals *object.Environment
+	budget  *object.Budget
+}
+
+// NewRuntime returns a runtime whose scripts print to os.Stdout and
+// os.Stderr; see SetOutput. Its methods must not be called concurrently.
+func NewRuntime() *Runtime {
+	r := &Runtime{globals: object.NewEnvironment(), budget: object.NewBudget()}
+	r.globals.SetBudget(r.budget)
+	r.SetOutput(os.Stdout, os.Stderr)
+	return r
+}
+
+// NewSharedRuntime returns a runtime whose methods may be called from many
+// goroutines at once, all of them running in the same global scope. Each
+// run is held to a budget of its own with the limits of Budget. Global
+// bindings are synchronized as described for object.NewSharedEnvironment;
+// the arrays and objects scripts share are not.
+func NewSharedRuntime() *Runtime {
+	r := &Runtime{globals: object.NewSharedEnvironment()}
+	r.budget = r.globals.Budget()
+	r.SetOutput(os.Stdout, os.Stderr)
+	return r
+}
+
+// SetOutput sends what scripts print with println, console.log, console.info
+// and console.debug to stdout, and console.warn and console.error to stderr.
+// A nil writer discards the output. It replaces the runtime's println and
+// console globals. In a runtime from NewSharedRuntime, scripts running at
+// once write to the writers from many goroutines at once, so they must be
+// safe for that.
+func (r *Runtime) SetOutput(stdout, stderr io.Writer) {
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+	if stderr == nil {
+		stderr = ioutil.Discard
+	}
+	r.globals.Set("println", eval.Println(stdout))
+	r.globals.Set("console", eval.Console(stdout, stderr))
+}
+
+// Budget returns the limits every run of the runtime is held to. Each run
+// and each Call gets a budget of its own with these limits, so a Call made
+// by a Go function during a run does not use up or reset the run's budget.
+// Change the limits only while nothing runs in the runtime.
+func (r *Runtime) Budget() *object.Budget {
+	return r.budget
+}
+
+// begin returns the scope a run that ctx may cancel starts in. It holds a
+// budget forked from the runtime's limits.
+func (r *Runtime) begin(ctx context.Context) *object.Environment {
+	budget := r.budget.Fork()
+	budget.Context = ctx
+	return object.NewRunEnvironment(r.globals, budget)
+}
+
+// Set binds name to value in the global scope, replacing any existing
+// binding, including one a script declared. Go values are converted with
+// ToValue.
+func (r *Runtime) Set(name string, value interface{}) error {
+	obj, err := ToValue(value)
+	if err != nil {
+		return fmt.Errorf("set %s: %w", name, err)
+	}
+	r.globals.Set(name, obj)
+	return nil
+}
+
+// Get returns the global named name. Use Export or ExportTo to convert it
+// to a Go value.
+func (r *Runtime) Get(name string) (object.Object, bool) {
+	return r.globals.Get(name)
+}
+
+// Register makes fn callable from scripts as the global function name. It
+// takes precedence over a builtin of the same name.
+func (r *Runtime) Register(name string, fn object.BuiltinFunction) {
+	r.globals.Set(name, &object.BuiltinObject{Fn: fn})
+}
+
+// Call calls the global function name with args converted by ToValue. Use
+// Export or ExportTo to convert the result; a script function can also be
+// exported to a Go func with ExportTo and called directly.
+func (r *Runtime) Call(name string, args ...interface{}) (object.Object, error) {
+	return r.CallContext(context.Background(), name, args...)
+}
+
+// CallContext is Call ending with an error when ctx is done.
+func (r *Runtime) CallContext(ctx context.Context, name string, args ...interface{}) (object.Object, error) {
+	fn, ok := r.globals.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("call %s: not defined", name)
+	}
+
+	values := make([]object.Object, len(args))
+	for i, arg := range args {
+		value, err := ToValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("call %s: argument %d: %w", name, i+1, err)
+		}
+		values[i] = value
+	}
+	scope := r.begin(ctx)
+	return eval.CallBudget(scope.Budget(), fn, nil, values...)
+}
+
+// RunString runs data in the runtime's global scope. Errors are reported as
+// by Run.
+func (r *Runtime) RunString(data string) (object.Object, error) {
+	return r.RunContext(context.Background(), data)
+}
+
+// RunContext is RunString ending with an error when ctx is done.
+func (r *Runtime) RunContext(ctx context.Context, data string) (object.Object, error) {
+	return Run(data, r.begin(ctx))
+}
@@ -0,0 +1,453 @@
+package js
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/bundgaard/js/object"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRuntimeState(t *testing.T) {
+	r := NewRuntime()
+	if _, err := r.RunString(`var count = 1;`); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := r.RunString(`count += 1;`); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	count, ok := r.Get("count")
+	if !ok || count.Inspect() != "2" {
+		t.Errorf("expected count to be 2. got %v", count)
+	}
+
+	r.Set("name", &object.StringObject{Value: "js"})
+	result, err := r.RunString("`hello ${name}`")
+	if err != nil || result.Inspect() != "hello js" {
+		t.Errorf("expected %q. got %v, %v", "hello js", result, err)
+	}
+}
+
+func TestRuntimeRegister(t *testing.T) {
+	r := NewRuntime()
+	var calls []string
+	r.Register("record", func(args ...object.Object) object.Object {
+		for _, arg := range args {
+			calls = append(calls, arg.Inspect())
+		}
+		return &object.NumberObject{Value: float64(len(calls))}
+	})
+	r.Register("len", func(args ...object.Object) object.Object {
+		return &object.StringObject{Value: "overridden"}
+	})
+
+	result, err := r.RunString(`record("a", "b"); record("c")`)
+	if err != nil || result.Inspect() != "3" {
+		t.Errorf("expected 3. got %v, %v", result, err)
+	}
+	if len(calls) != 3 || calls[2] != "c" {
+		t.Errorf("expected the Go function to be called. got %v", calls)
+	}
+
+	if result, err := r.RunString(`len("abc")`); err != nil || result.Inspect() != "overridden" {
+		t.Errorf("expected the registered function to override the builtin. got %v, %v", result, err)
+	}
+}
+
+func TestRuntimeIsolation(t *testing.T) {
+	a, b := NewRuntime(), NewRuntime()
+	a.Register("secret", func(args ...object.Object) object.Object {
+		return &object.NumberObject{Value: 1}
+	})
+	if _, err := a.RunString(`var x = 1;`); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if _, ok := b.Get("x"); ok {
+		t.Errorf("expected x to be private to the runtime that declared it")
+	}
+	if _, err := b.RunString(`secret()`); err == nil {
+		t.Errorf("expected secret to be undefined in another runtime")
+	}
+	if result, err := b.RunString(`len("ab")`); err != nil || result.Inspect() != "2" {
+		t.Errorf("expected the default builtins. got %v, %v", result, err)
+	}
+}
+
+func TestRuntimeCall(t *testing.T) {
+	r := NewRuntime()
+	_, err := r.RunString(`
+fn handler(req) {
+  if (req.path == "/") { throw new Error("forbidden"); }
+  return {status: 200, body: "hello " + req.path};
+}
+`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	result, err := r.Call("handler", map[string]string{"path": "/users"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var response struct {
+		Status int    `js:"status"`
+		Body   string `js:"body"`
+	}
+	if err := ExportTo(result, &response); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if response.Status != 200 || response.Body != "hello /users" {
+		t.Errorf("unexpected response %+v", response)
+	}
+
+	if _, err := r.Call("handler", map[string]string{"path": "/"}); err == nil || err.Error() != "3:26: Error: forbidden" {
+		t.Errorf("expected the thrown error. got %v", err)
+	}
+	if _, err := r.Call("missing"); err == nil {
+		t.Errorf("expected an error calling an undefined function")
+	}
+}
+
+func TestRuntimeLimits(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Budget   object.Budget
+		Input    string
+		Expected string
+	}{
+		{"steps", object.Budget{MaxSteps: 1000}, `while (true) {}`, "RangeError: step limit of 1000 exceeded"},
+		{"steps not caught", object.Budget{MaxSteps: 1000}, `try { while (true) {} } catch (e) {} finally { var x = 1; }`, "RangeError: step limit of 1000 exceeded"},
+		{"allocation", object.Budget{MaxAllocation: 1 << 20}, `var s = "ab"; while (true) { s = s + s; }`, "RangeError: allocation limit of 1048576 exceeded"},
+		{"array growth", object.Budget{MaxAllocation: 1000}, `var a = []; a[100000] = 1;`, "RangeError: allocation limit of 1000 exceeded"},
+		{"call depth", object.Budget{MaxCallDepth: 100}, `fn f(n) { return f(n + 1); } f(0)`, "RangeError: maximum call stack size exceeded"},
+	}
+
+	for _, test := range tests {
+		r := NewRuntime()
+		*r.Budget() = test.Budget
+		_, err := r.RunString(test.Input)
+		if err == nil || !strings.Contains(err.Error(), test.Expected) {
+			t.Errorf("%s: expected %q. got %v", test.Name, test.Expected, err)
+		}
+	}
+}
+
+func TestRuntimeCallDepthIsCatchable(t *testing.T) {
+	r := NewRuntime()
+	result, err := r.RunString(`fn f() { return f(); } var r = null; try { f(); } catch (e) { r = e.name; } r`)
+	if err != nil || result.Inspect() != "RangeError" {
+		t.Errorf("expected a catchable RangeError. got %v, %v", result, err)
+	}
+}
+
+func TestRuntimeContext(t *testing.T) {
+	r := NewRuntime()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := r.RunContext(ctx, `while (true) {}`)
+	if err == nil || !strings.Contains(err.Error(), "context deadline exceeded") {
+		t.Errorf("expected the deadline to stop the script. got %v", err)
+	}
+
+	// The next run starts afresh.
+	if result, err := r.RunString(`1 + 1`); err != nil || result.Inspect() != "2" {
+		t.Errorf("expected 2. got %v, %v", result, err)
+	}
+}
+
+func TestRuntimeNestedCall(t *testing.T) {
+	r := NewRuntime()
+	r.Register("hook", func(args ...object.Object) object.Object {
+		if _, err := r.Call("noop"); err != nil {
+			return &object.Error{Message: err.Error()}
+		}
+		return nil
+	})
+	if _, err := r.RunString(`fn noop() {}`); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// A Call made during a run leaves the run's context and steps alone.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.RunContext(ctx, `hook(); while (true) {}`); err == nil || !strings.Contains(err.Error(), "context deadline exceeded") {
+		t.Errorf("expected the deadline to stop the script. got %v", err)
+	}
+
+	r.Budget().MaxSteps = 5000
+	_, err := r.RunString(`var i = 0; while (true) { i++; if (i % 100 == 0) { hook(); } }`)
+	if err == nil || !strings.Contains(err.Error(), "step limit of 5000 exceeded") {
+		t.Errorf("expected the step limit. got %v", err)
+	}
+}
+
+func TestRuntimeCallContext(t *testing.T) {
+	r := NewRuntime()
+	if _, err := r.RunString(`fn spin() { while (true) {} }`); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.CallContext(ctx, "spin"); err == nil || !strings.Contains(err.Error(), "context deadline exceeded") {
+		t.Errorf("expected the deadline to stop the call. got %v", err)
+	}
+}
+
+func TestRuntimeCompiledFunctions(t *testing.T) {
+	// Functions declared by a compiled program are called from Go, from
+	// interpreted scripts and as Go callbacks like any other.
+	r := NewRuntime()
+	program, err := Compile(`
+function handler(x) { return x * 2; }
+function fail() { throw new TypeError("bad"); }
+function Point(x) { this.x = x; }
+`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := r.RunProgram(program); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if result, err := r.Call("handler", 21); err != nil || result.Inspect() != "42" {
+		t.Errorf("expected Call to return 42. got %v, %v", result, err)
+	}
+	if _, err := r.Call("fail"); err == nil || !strings.Contains(err.Error(), "TypeError: bad") {
+		t.Errorf("expected the thrown error. got %v", err)
+	}
+
+	if err := r.Set("apply", func(f func(float64) float64, x float64) float64 { return f(x) }); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	result, err := r.RunString(`[handler(2), apply(handler, 5), new Point(3).x, typeof handler]`)
+	if err != nil || result.Inspect() != "[4, 10, 3, function]" {
+		t.Errorf("expected the script to call handler. got %v, %v", result, err)
+	}
+	_, err = r.RunString(`fail()`)
+	if serr, ok := err.(*object.Error); !ok || serr.Trace() != "TypeError: bad\n    at fail (3:19)\n    at 1:1" {
+		t.Errorf("expected the trace to name fail. got %v", err)
+	}
+
+	fn, _ := r.Get("handler")
+	var double func(float64) (float64, error)
+	if err := ExportTo(fn, &double); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if n, err := double(4); err != nil || n != 8 {
+		t.Errorf("expected 8. got %v, %v", n, err)
+	}
+}
+
+func TestRuntimeOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	r := NewRuntime()
+	r.SetOutput(&stdout, &stderr)
+
+	_, err := r.RunString(`
+println("hello", 1, [2, 3]);
+console.log("log");
+console.info("info");
+console.debug("debug");
+console.warn("warn", true);
+console.error("error");
+var a = [1];
+a[1] = a;
+var o = {};
+o.self = o;
+console.log(a, o, [o, o]);
+`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if expected := "hello 1 [2, 3]\nlog\ninfo\ndebug\n[1, [Circular]] {self: [Circular]} [{self: [Circular]}, {self: [Circular]}]\n"; stdout.String() != expected {
+		t.Errorf("expected stdout %q. got %q", expected, stdout.String())
+	}
+	if expected := "warn true\nerror\n"; stderr.String() != expected {
+		t.Errorf("expected stderr %q. got %q", expected, stderr.String())
+	}
+
+	other := NewRuntime()
+	other.SetOutput(nil, nil)
+	if _, err := other.RunString(`console.log("discarded"); println("discarded")`); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if strings.Contains(stdout.String(), "discarded") {
+		t.Errorf("expected runtimes to have separate output")
+	}
+}
+
+func TestSharedRuntimeConcurrent(t *testing.T) {
+	r := NewSharedRuntime()
+	r.SetOutput(nil, nil)
+	var mu sync.Mutex
+	total := 0
+	r.Register("add", func(args ...object.Object) object.Object {
+		mu.Lock()
+		defer mu.Unlock()
+		total += int(args[0].(*object.NumberObject).Value)
+		return nil
+	})
+	if _, err := r.RunString(`
+function fib(n) { return n < 2 ? n : fib(n - 1) + fib(n - 2); }
+var makeCounter = function() {
+	let count = 0;
+	return () => ++count;
+};
+var shared = makeCounter();
+let base = 10;
+`); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	program, err := Compile(`var last = fib(n); add(last); shared(); fib(n) + base;`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	const workers = 8
+	const rounds = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds*4)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				name := fmt.Sprintf("v%d_%d", w, i)
+				if _, err := r.RunString(fmt.Sprintf(`var %s = makeCounter(); %s(); for (let i = 0; i < 3; i++) { %s(); }`, name, name, name)); err != nil {
+					errs <- err
+				}
+				if v, _ := r.Get(name); v == nil || v.Type() != object.FunctionType {
+					errs <- fmt.Errorf("expected %s to be a function. got %v", name, v)
+				}
+				if err := r.Set("n", 10); err != nil {
+					errs <- err
+				}
+				if result, err := r.RunProgram(program); err != nil || result.Inspect() != "65" {
+					errs <- fmt.Errorf("expected 65. got %v, %v", result, err)
+				}
+				if result, err := r.Call("fib", 12); err != nil || result.Inspect() != "144" {
+					errs <- fmt.Errorf("expected 144. got %v, %v", result, err)
+				}
+				if result, err := r.RunString(`println(typeof base); add(1); fib(10);`); err != nil || result.Inspect() != "55" {
+					errs <- fmt.Errorf("expected 55. got %v, %v", result, err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if expected := workers * rounds * 56; total != expected {
+		t.Errorf("expected add to be called with %d in all. got %d", expected, total)
+	}
+	// ++count is not atomic, so runs calling shared at once may lose
+	// increments; add counts the runs exactly instead.
+	count, err := r.RunString(`shared();`)
+	if n, ok := count.(*object.NumberObject); err != nil || !ok || n.Value < 1 || n.Value > workers*rounds+1 {
+		t.Errorf("expected shared to count at most every run. got %v, %v", count, err)
+	}
+}
+
+func TestSharedRuntimeBudgets(t *testing.T) {
+	r := NewSharedRuntime()
+	r.Budget().MaxSteps = 100000
+	if _, err := r.RunString(`function spin() { while (true) {} }`); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	wg.Add(3)
+	go func() {
+		// Each run has its own steps, so many small runs stay in budget.
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if _, err := r.RunString(`var x = 0; for (let i = 0; i < 100; i++) { x += i; }`); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if _, err := r.RunString(`spin();`); err == nil || !strings.Contains(err.Error(), "step limit") {
+			errs <- fmt.Errorf("expected the step limit. got %v", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := r.RunContext(ctx, `spin();`); err == nil || !strings.Contains(err.Error(), "cancelled") {
+			errs <- fmt.Errorf("expected the run to be cancelled. got %v", err)
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestSharedRuntimeCallDepth(t *testing.T) {
+	r := NewSharedRuntime()
+	r.Budget().MaxCallDepth = 50
+	result, err := r.RunString(`fn rec(n) { return n == 0 ? 0 : 1 + rec(n - 1); }`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var rec func(float64) (float64, error)
+	if err := ExportTo(result, &rec); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// Concurrent calls do not add up to each other's call depth.
+	var wg sync.WaitGroup
+	errs := make(chan error, 16*10)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if n, err := rec(40); err != nil || n != 40 {
+					errs <- fmt.Errorf("expected 40. got %v, %v", n, err)
+				}
+				if _, err := r.Call("rec", 40); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestBudgetEnterCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	budget := object.NewBudget()
+	budget.MaxCallDepth = 1
+	budget.Context = ctx
+	for i := 0; i < 3; i++ {
+		if err := budget.Enter(); err == nil {
+			t.Fatalf("expected the cancelled context to fail the call")
+		}
+	}
+	// The failed calls did not use up the call depth.
+	budget.Context = nil
+	if err := budget.Enter(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
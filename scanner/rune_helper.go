@@ -1,6 +1,9 @@
 package scanner
 
-import "unicode"
+import (
+	"unicode"
+	"unicode/utf8"
+)
 
 func isDigit(c rune) bool {
 	return '0' <= c && c <= '9'
@@ -8,7 +11,20 @@ func isDigit(c rune) bool {
 func isLetter(ch rune) bool {
 	return 'a' <= ch && ch <= 'z' ||
 		'A' <= ch && ch <= 'Z' ||
-		ch == '_'
+		ch == '_' || ch == '$' ||
+		ch >= utf8.RuneSelf && (unicode.IsLetter(ch) || unicode.Is(unicode.Nl, ch))
+}
+func isHexDigit(c rune) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+func hexValue(c rune) rune {
+	switch {
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10
+	}
+	return c - '0'
 }
 func isSpace(r rune) bool {
 	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
@@ -18,6 +34,10 @@ func isNumber(r rune) bool {
 	return '0' <= r && r <= '9'
 }
 
+// isAlphaNum reports whether r can continue an identifier: a letter, a
+// digit, a combining mark, a connector such as _ or one of the zero width
+// joiners.
 func isAlphaNum(r rune) bool {
-	return r == '_' || isNumber(r) || unicode.IsLetter(r)
+	return isLetter(r) || isNumber(r) ||
+		r >= utf8.RuneSelf && (unicode.In(r, unicode.Nd, unicode.Mn, unicode.Mc, unicode.Pc) || r == '\u200c' || r == '\u200d')
 }
@@ -8,41 +8,68 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
 
-type Pos struct {
-	Line   int
-	Column int
-}
-
 const EofRune = -1
 
 type Scanner struct {
-	rd           io.RuneReader
-	Buf          bytes.Buffer
-	peeking      bool
-	peekRune     rune
-	last         rune
+	rd       io.RuneReader
+	Buf      bytes.Buffer
+	peeking  bool
+	peekRune rune
+	peekPos  token.Position
+	last     rune
+	lastPos  token.Position
+	start    token.Position
+	offset   int
+
+	// pending is a token scanned ahead of the one returned last.
+	pending *token.Token
+
+	// templates holds, for each template substitution being scanned, the
+	// number of braces opened inside it and not yet closed.
+	templates []int
+
+	// Line and Column are the position of the next rune read from the input.
 	Line, Column uint
+
+	// Error, if set, is called for each malformed token. The scanner
+	// returns such tokens as token.Illegal.
+	Error func(tok *token.Token, msg string)
 }
 
 func (s *Scanner) read() rune {
 	if s.peeking {
 		s.peeking = false
+		s.lastPos = s.peekPos
 		return s.peekRune
 	}
 	return s.readChar()
 }
 func (s *Scanner) readChar() rune {
-	r, _, err := s.rd.ReadRune()
+	s.lastPos = s.position()
+	r, size, err := s.rd.ReadRune()
 	if err != nil {
 		if err != io.EOF {
 			fmt.Fprintln(os.Stderr)
 		}
 		r = EofRune
 	}
+
+	s.offset += size
+	switch r {
+	case EofRune:
+	case '\n':
+		s.Line++
+		s.Column = 1
+	default:
+		s.Column++
+	}
 	s.last = r
 	return r
 }
@@ -54,12 +81,53 @@ func (s *Scanner) peek() rune {
 	r := s.read()
 	s.peeking = true
 	s.peekRune = r
+	s.peekPos = s.lastPos
 	return r
 }
 
 func (s *Scanner) back(r rune) {
 	s.peeking = true
 	s.peekRune = r
+	s.peekPos = s.lastPos
+}
+
+// illegal returns an Illegal token for the malformed text value, reporting
+// msg to the error handler.
+func (s *Scanner) illegal(value, msg string) *token.Token {
+	tk := token.New(token.Illegal, value)
+	tk.Start, tk.End = s.start, s.here()
+	if s.Error != nil {
+		s.Error(tk, msg)
+	}
+	return tk
+}
+
+// position returns the position of the next rune read from the input.
+func (s *Scanner) position() token.Position {
+	return token.Position{Offset: s.offset, Line: int(s.Line), Column: int(s.Column)}
+}
+
+// here returns the position of the next rune NextToken will look at.
+func (s *Scanner) here() token.Position {
+	if s.peeking {
+		return s.peekPos
+	}
+	return s.position()
+}
+
+// NextToken returns the next token in the input, with its start and end
+// positions set.
+func (s *Scanner) NextToken() *token.Token {
+	if tk := s.pending; tk != nil {
+		s.pending = nil
+		return tk
+	}
+
+	tk := s.scan()
+	if !tk.Start.IsValid() {
+		tk.Start, tk.End = s.start, s.here()
+	}
+	return tk
 }
 
 func (s *Scanner) accum(r rune, valid func(rune) bool) {
@@ -78,29 +146,130 @@ func (s *Scanner) accum(r rune, valid func(rune) bool) {
 	}
 }
 
-func (s *Scanner) NextToken() *token.Token {
+func (s *Scanner) scan() *token.Token {
 	for {
 		r := s.read()
+		s.start = s.lastPos
 		switch {
 		case isSpace(r):
 		case r == ':':
 			return token.New(token.Colon, ":")
 		case r == '=':
+			if s.peek() == '>' {
+				s.read()
+				return token.New(token.Arrow, "=>")
+			}
+			if s.peek() == '=' {
+				s.read()
+				if s.peek() == '=' {
+					s.read()
+					return token.New(token.StrictEqual, "===")
+				}
+				return token.New(token.Equal, "==")
+			}
 			return token.New(token.Assign, "=")
+		case r == '!':
+			if s.peek() == '=' {
+				s.read()
+				if s.peek() == '=' {
+					s.read()
+					return token.New(token.StrictNotEqual, "!==")
+				}
+				return token.New(token.NotEqual, "!=")
+			}
+			return token.New(token.Bang, "!")
+		case r == '&':
+			if s.peek() == '&' {
+				s.read()
+				return token.New(token.And, "&&")
+			}
+			return s.illegal("&", "unexpected character '&'")
+		case r == '|':
+			if s.peek() == '|' {
+				s.read()
+				return token.New(token.Or, "||")
+			}
+			return s.illegal("|", "unexpected character '|'")
+		case r == '?':
+			switch s.peek() {
+			case '?':
+				s.read()
+				return token.New(token.Nullish, "??")
+			case '.':
+				s.read()
+				if isDigit(s.peek()) {
+					// a?.5:1 is a conditional, not optional chaining
+					question := token.New(token.Question, "?")
+					question.Start, question.End = s.start, s.lastPos
+					number := token.New(token.Number, s.readLiteral('.'))
+					number.Start, number.End = question.End, s.here()
+					s.pending = number
+					return question
+				}
+				return token.New(token.OptionalChain, "?.")
+			}
+			return token.New(token.Question, "?")
+		case r == '<':
+			if s.peek() == '=' {
+				s.read()
+				return token.New(token.LessEqual, "<=")
+			}
+			return token.New(token.Less, "<")
+		case r == '>':
+			if s.peek() == '=' {
+				s.read()
+				return token.New(token.GreaterEqual, ">=")
+			}
+			return token.New(token.Greater, ">")
 		case r == EofRune:
 			return token.New(token.EOF, "EOF")
 		case r == ';':
 			return token.New(token.Semi, ";")
 		case r == '.':
+			switch s.peek() {
+			case '.':
+				s.read()
+				if s.peek() != '.' {
+					return s.illegal("..", "unexpected \"..\"")
+				}
+				s.read()
+				return token.New(token.Ellipsis, "...")
+			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				return token.New(token.Number, s.readLiteral(r))
+			}
 			return token.New(token.Dot, ".")
 		case r == ',':
 			return token.New(token.Comma, ",")
 		case r == '"' || r == '\'':
-			return token.New(token.String, s.readString(r))
+			return s.readString(r)
+		case r == '`':
+			return s.readTemplate(token.Template, token.TemplateHead)
 		case r == '+':
+			switch s.peek() {
+			case '+':
+				s.read()
+				return token.New(token.Increment, "++")
+			case '=':
+				s.read()
+				return token.New(token.AddAssign, "+=")
+			}
 			return token.New(token.Add, "+")
 		case r == '-':
+			switch s.peek() {
+			case '-':
+				s.read()
+				return token.New(token.Decrement, "--")
+			case '=':
+				s.read()
+				return token.New(token.SubAssign, "-=")
+			}
 			return token.New(token.Sub, "-")
+		case r == '%':
+			if s.peek() == '=' {
+				s.read()
+				return token.New(token.ModAssign, "%=")
+			}
+			return token.New(token.Mod, "%")
 		case r == '/':
 			pr := s.peek()
 			if pr == '/' {
@@ -112,27 +281,56 @@ func (s *Scanner) NextToken() *token.Token {
 				continue
 			} else if pr == '*' {
 				// read to */
-
+				s.read()
 				for {
-					if s.last == '*' && s.peek() == '/' {
+					r := s.read()
+					if r == EofRune {
+						return s.illegal("/*", "unterminated comment")
+					}
+					if r == '*' && s.peek() == '/' {
 						s.read()
 						break
 					}
-					s.read()
 				}
 				continue
 
+			} else if pr == '=' {
+				s.read()
+				return token.New(token.DivAssign, "/=")
 			}
 			return token.New(token.Div, "/")
 		case r == '*':
+			switch s.peek() {
+			case '=':
+				s.read()
+				return token.New(token.MulAssign, "*=")
+			case '*':
+				s.read()
+				if s.peek() == '=' {
+					s.read()
+					return token.New(token.PowAssign, "**=")
+				}
+				return token.New(token.Pow, "**")
+			}
 			return token.New(token.Mul, "*")
 		case r == '(':
 			return token.New(token.OpenParen, "(")
 		case r == ')':
 			return token.New(token.CloseParen, ")")
 		case r == '{':
+			if n := len(s.templates); n > 0 {
+				s.templates[n-1]++
+			}
 			return token.New(token.OpenCurly, "{")
 		case r == '}':
+			if n := len(s.templates); n > 0 {
+				if s.templates[n-1] == 0 {
+					// end of a ${} substitution; the template continues
+					s.templates = s.templates[:n-1]
+					return s.readTemplate(token.TemplateTail, token.TemplateMiddle)
+				}
+				s.templates[n-1]--
+			}
 			return token.New(token.CloseCurly, "}")
 		case r == '[':
 			return token.New(token.OpenBracket, "[")
@@ -153,11 +351,9 @@ func (s *Scanner) NextToken() *token.Token {
 				return tk
 			} else if isDigit(r) {
 				tk.Type = token.Number
-				tk.Value = s.readLiteral()
+				tk.Value = s.readLiteral(r)
 			} else {
-				tk.Type = token.Illegal
-				tk.Value = string(s.last)
-
+				return s.illegal(string(r), fmt.Sprintf("unexpected character %q", r))
 			}
 			return tk
 		}
@@ -165,41 +361,207 @@ func (s *Scanner) NextToken() *token.Token {
 
 }
 
-func (s *Scanner) readString(quote rune) string {
+// readString reads a string literal up to the closing quote, replacing
+// escape sequences with the characters they stand for.
+func (s *Scanner) readString(quote rune) *token.Token {
 	s.Buf.Reset()
+	for {
+		r := s.read()
+		switch r {
+		case quote:
+			return token.New(token.String, s.Buf.String())
+		case EofRune, '\n', '\r':
+			s.back(r)
+			return s.illegal(string(quote), "unterminated string literal")
+		case '\\':
+			if !s.readEscape() {
+				return s.illegal(string(quote), "invalid escape sequence")
+			}
+		default:
+			s.Buf.WriteRune(r)
+		}
+	}
+}
 
-	var escaped bool
-	// "foobar"
-	// "\"foobar\""
+// readTemplate reads template text after a backtick or after the } that
+// ends a substitution, up to the closing backtick or the next ${. The token
+// has type end or substitution depending on which it finds.
+func (s *Scanner) readTemplate(end, substitution token.Type) *token.Token {
+	s.Buf.Reset()
 	for {
-		// TODO: fix so we read escape characters \<?>
-		if !escaped && s.peek() == quote {
-			break
+		r := s.read()
+		switch r {
+		case '`':
+			return token.New(end, s.Buf.String())
+		case '$':
+			if s.peek() == '{' {
+				s.read()
+				s.templates = append(s.templates, 0)
+				return token.New(substitution, s.Buf.String())
+			}
+			s.Buf.WriteRune(r)
+		case EofRune:
+			return s.illegal("`", "unterminated template literal")
+		case '\\':
+			if !s.readEscape() {
+				return s.illegal("`", "invalid escape sequence")
+			}
+		case '\r':
+			// \r and \r\n in templates are read as \n
+			if s.peek() == '\n' {
+				s.read()
+			}
+			s.Buf.WriteRune('\n')
+		default:
+			s.Buf.WriteRune(r)
 		}
+	}
+}
 
-		// Are we escaped ?
-		// Escaped characters would be \\, \' \", \/
-		if s.peek() == '\\' {
-			r := s.read() // consume slash
-			if s.peek() == quote {
-				s.Buf.WriteRune(r)
+var escapes = map[rune]rune{
+	'n': '\n',
+	't': '\t',
+	'r': '\r',
+	'b': '\b',
+	'f': '\f',
+	'v': '\v',
+}
 
-				escaped = !escaped
+// readEscape reads the escape sequence after a backslash and writes the
+// character it stands for to the buffer. It reports whether the sequence
+// is valid.
+func (s *Scanner) readEscape() bool {
+	r := s.read()
+	if e, ok := escapes[r]; ok {
+		s.Buf.WriteRune(e)
+		return true
+	}
+
+	switch r {
+	case EofRune:
+		return false
+	case '0':
+		if isDigit(s.peek()) {
+			return false // legacy octal escapes are not supported
+		}
+		s.Buf.WriteRune(0)
+	case '\r':
+		// line continuation
+		if s.peek() == '\n' {
+			s.read()
+		}
+	case '\n', '\u2028', '\u2029':
+		// line continuation
+	case 'x':
+		code, ok := s.readHex(2)
+		if !ok {
+			return false
+		}
+		s.Buf.WriteRune(code)
+	case 'u':
+		code, ok := s.readUnicodeEscape()
+		if !ok {
+			return false
+		}
+		// A surrogate pair written as two escapes is one character.
+		if utf16.IsSurrogate(code) && s.peek() == '\\' {
+			s.read()
+			if s.peek() != 'u' {
+				s.Buf.WriteRune(utf8.RuneError)
+				return s.readEscape()
+			}
+			s.read()
+			low, ok := s.readUnicodeEscape()
+			if !ok {
+				return false
 			}
+			if pair := utf16.DecodeRune(code, low); pair != utf8.RuneError {
+				s.Buf.WriteRune(pair)
+				return true
+			}
+			s.Buf.WriteRune(utf8.RuneError)
+			code = low
 		}
-		r := s.read()
+		s.Buf.WriteRune(code)
+	default:
+		// any other character stands for itself: \" \' \\ \`
 		s.Buf.WriteRune(r)
 	}
-	s.read() // eat quote
+	return true
+}
 
-	return s.Buf.String()
+// readUnicodeEscape reads the XXXX or {X...} part of a \u escape.
+func (s *Scanner) readUnicodeEscape() (rune, bool) {
+	if s.peek() != '{' {
+		return s.readHex(4)
+	}
+	s.read()
+
+	var code rune
+	digits := 0
+	for r := s.read(); r != '}'; r = s.read() {
+		if !isHexDigit(r) {
+			return 0, false
+		}
+		code = code*16 + hexValue(r)
+		digits++
+		if code > unicode.MaxRune {
+			return 0, false
+		}
+	}
+	return code, digits > 0
+}
+
+// readHex reads exactly n hex digits.
+func (s *Scanner) readHex(n int) (rune, bool) {
+	var code rune
+	for i := 0; i < n; i++ {
+		if !isHexDigit(s.peek()) {
+			return 0, false
+		}
+		code = code*16 + hexValue(s.read())
+	}
+	return code, true
 }
 
-func (s *Scanner) readLiteral() string {
-	s.accum(s.last, isDigit)
+// readLiteral reads a number: decimal with optional fraction and exponent,
+// or an integer with a 0x, 0o or 0b prefix. Digits may be separated by _;
+// the parser checks the separators are well placed.
+func (s *Scanner) readLiteral(first rune) string {
+	s.Buf.Reset()
+	s.Buf.WriteRune(first)
+
+	if first == '0' {
+		switch s.peek() {
+		case 'x', 'X', 'o', 'O', 'b', 'B':
+			s.Buf.WriteRune(s.read())
+			s.readDigits(isHexDigit)
+			return s.Buf.String()
+		}
+	}
+
+	s.readDigits(isDigit)
+	if first != '.' && s.peek() == '.' {
+		s.Buf.WriteRune(s.read())
+		s.readDigits(isDigit)
+	}
+
+	if r := s.peek(); r == 'e' || r == 'E' {
+		s.Buf.WriteRune(s.read())
+		if r := s.peek(); r == '+' || r == '-' {
+			s.Buf.WriteRune(s.read())
+		}
+		s.readDigits(isDigit)
+	}
 	return s.Buf.String()
 }
 
+func (s *Scanner) readDigits(valid func(rune) bool) {
+	for r := s.peek(); valid(r) || r == '_'; r = s.peek() {
+		s.Buf.WriteRune(s.read())
+	}
+}
+
 func (s *Scanner) readName() string {
 	s.accum(s.last, isAlphaNum)
 	return s.Buf.String()
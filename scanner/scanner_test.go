@@ -24,8 +24,8 @@ func TestScannerWithEscaped(t *testing.T) {
 	tk = s.NextToken()
 	isToken(t, tk, token2.String)
 
-	if tk.Value != "Hello, \\\"World!\\\" StringT" {
-		t.Errorf("expected %v (%d). got %v (%d)", "Hello, \\\"World!\\\"", len("Hello, \\\"World!\\\""), []byte(tk.Value), len(tk.Value))
+	if tk.Value != "Hello, \"World!\" StringT" {
+		t.Errorf("expected %v (%d). got %v (%d)", "Hello, \"World!\" StringT", len("Hello, \"World!\" StringT"), []byte(tk.Value), len(tk.Value))
 	}
 }
 func isToken(t *testing.T, tk *token2.Token, expected token2.Type) {
@@ -96,3 +96,231 @@ func TestScanner(t *testing.T) {
 		t.Errorf("expected Semi. Got %q", token.Type)
 	}
 }
+
+func TestScannerComparison(t *testing.T) {
+	s := New(strings.NewReader(`== != === !== < > <= >= ! =`))
+
+	expected := []token2.Type{
+		token2.Equal,
+		token2.NotEqual,
+		token2.StrictEqual,
+		token2.StrictNotEqual,
+		token2.Less,
+		token2.Greater,
+		token2.LessEqual,
+		token2.GreaterEqual,
+		token2.Bang,
+		token2.Assign,
+		token2.EOF,
+	}
+	for _, tt := range expected {
+		isToken(t, s.NextToken(), tt)
+	}
+}
+
+func TestScannerNumbers(t *testing.T) {
+	tests := []string{"0", "42", "1.5", ".5", "1e3", "2.5E-3", "1e+9", "0x1F", "0o17", "0b1010", "1_000"}
+	for _, test := range tests {
+		s := New(strings.NewReader(test + ";"))
+		tk := s.NextToken()
+		isToken(t, tk, token2.Number)
+		if tk.Value != test {
+			t.Errorf("expected %q. got %q", test, tk.Value)
+		}
+		isToken(t, s.NextToken(), token2.Semi)
+	}
+}
+
+func TestScannerPositions(t *testing.T) {
+	s := New(strings.NewReader("var x = 1;\n  x += 22; // done\n\"s\" /* a\nb */ y"))
+
+	pos := func(offset, line, column int) token2.Position {
+		return token2.Position{Offset: offset, Line: line, Column: column}
+	}
+	expected := []struct {
+		Start, End token2.Position
+	}{
+		{pos(0, 1, 1), pos(3, 1, 4)},
+		{pos(4, 1, 5), pos(5, 1, 6)},
+		{pos(6, 1, 7), pos(7, 1, 8)},
+		{pos(8, 1, 9), pos(9, 1, 10)},
+		{pos(9, 1, 10), pos(10, 1, 11)},
+		{pos(13, 2, 3), pos(14, 2, 4)},
+		{pos(15, 2, 5), pos(17, 2, 7)},
+		{pos(18, 2, 8), pos(20, 2, 10)},
+		{pos(20, 2, 10), pos(21, 2, 11)},
+		{pos(30, 3, 1), pos(33, 3, 4)},
+		{pos(44, 4, 6), pos(45, 4, 7)},
+		{pos(45, 4, 7), pos(45, 4, 7)},
+	}
+	for _, pos := range expected {
+		tk := s.NextToken()
+		if tk.Start != pos.Start || tk.End != pos.End {
+			t.Errorf("%v: expected %v-%v. got %v-%v", tk.Value, pos.Start, pos.End, tk.Start, tk.End)
+		}
+		if tk.Start.Offset != pos.Start.Offset || tk.End.Offset != pos.End.Offset {
+			t.Errorf("%v: expected offsets %d-%d. got %d-%d", tk.Value, pos.Start.Offset, pos.End.Offset, tk.Start.Offset, tk.End.Offset)
+		}
+	}
+}
+
+func TestScannerErrors(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Value    string
+		Expected string
+	}{
+		{"a @", "@", `unexpected character '@'`},
+		{"/* open", "/*", "unterminated comment"},
+	}
+
+	for _, test := range tests {
+		var msg string
+		s := New(strings.NewReader(test.Input))
+		s.Error = func(tk *token2.Token, m string) {
+			msg = m
+		}
+
+		var tk *token2.Token
+		for tk = s.NextToken(); tk.Type != token2.Illegal && tk.Type != token2.EOF; tk = s.NextToken() {
+		}
+		isToken(t, tk, token2.Illegal)
+		if tk.Value != test.Value || msg != test.Expected {
+			t.Errorf("%q: expected %q (%s). got %q (%s)", test.Input, test.Value, test.Expected, tk.Value, msg)
+		}
+	}
+}
+
+func TestScannerStringEscapes(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`"a\nb\tc"`, "a\nb\tc"},
+		{`'\\ \' \" \/'`, `\ ' " /`},
+		{`"\r\b\f\v\0"`, "\r\b\f\v\x00"},
+		{`"\x41B\u{43}\u{1F600}"`, "ABC\U0001F600"},
+		{`"😀"`, "\U0001F600"},
+		{"\"line \\\ncontinued\"", "line continued"},
+		{`"\q"`, "q"},
+		{`"æøå ✓"`, "æøå ✓"},
+	}
+
+	for _, test := range tests {
+		s := New(strings.NewReader(test.Input))
+		tk := s.NextToken()
+		isToken(t, tk, token2.String)
+		if tk.Value != test.Expected {
+			t.Errorf("%s: expected %q. got %q", test.Input, test.Expected, tk.Value)
+		}
+		isToken(t, s.NextToken(), token2.EOF)
+	}
+}
+
+func TestScannerStringErrors(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`"never closed`, "unterminated string literal"},
+		{"'broken\nline'", "unterminated string literal"},
+		{`"\x4"`, "invalid escape sequence"},
+		{`"\u{110000}"`, "invalid escape sequence"},
+		{"`never closed", "unterminated template literal"},
+	}
+
+	for _, test := range tests {
+		var msg string
+		s := New(strings.NewReader(test.Input))
+		s.Error = func(tk *token2.Token, m string) {
+			msg = m
+		}
+		isToken(t, s.NextToken(), token2.Illegal)
+		if msg != test.Expected {
+			t.Errorf("%q: expected %q. got %q", test.Input, test.Expected, msg)
+		}
+	}
+}
+
+func TestScannerTemplate(t *testing.T) {
+	s := New(strings.NewReader("`a ${x + {b: 1}.b} c ${`in${y}`}\\n`"))
+
+	expected := []struct {
+		Type  token2.Type
+		Value string
+	}{
+		{token2.TemplateHead, "a "},
+		{token2.Ident, "x"},
+		{token2.Add, "+"},
+		{token2.OpenCurly, "{"},
+		{token2.Ident, "b"},
+		{token2.Colon, ":"},
+		{token2.Number, "1"},
+		{token2.CloseCurly, "}"},
+		{token2.Dot, "."},
+		{token2.Ident, "b"},
+		{token2.TemplateMiddle, " c "},
+		{token2.TemplateHead, "in"},
+		{token2.Ident, "y"},
+		{token2.TemplateTail, ""},
+		{token2.TemplateTail, "\n"},
+		{token2.EOF, "EOF"},
+	}
+	for _, tt := range expected {
+		tk := s.NextToken()
+		if tk.Type != tt.Type || tk.Value != tt.Value {
+			t.Errorf("expected %s %q. got %s %q", tt.Type, tt.Value, tk.Type, tk.Value)
+		}
+	}
+}
+
+func TestScannerUnicodeIdentifiers(t *testing.T) {
+	s := New(strings.NewReader("$ _$x café π2 名前 á"))
+	for _, expected := range []string{"$", "_$x", "café", "π2", "名前", "á"} {
+		tk := s.NextToken()
+		isToken(t, tk, token2.Ident)
+		if tk.Value != expected {
+			t.Errorf("expected %q. got %q", expected, tk.Value)
+		}
+	}
+}
+
+func TestScannerLogical(t *testing.T) {
+	s := New(strings.NewReader(`&& || ?? ? ?. a?.5:1 typeof`))
+
+	expected := []token2.Type{
+		token2.And,
+		token2.Or,
+		token2.Nullish,
+		token2.Question,
+		token2.OptionalChain,
+		token2.Ident,
+		token2.Question,
+		token2.Number,
+		token2.Colon,
+		token2.Number,
+		token2.Typeof,
+		token2.EOF,
+	}
+	for _, tt := range expected {
+		isToken(t, s.NextToken(), tt)
+	}
+}
+
+func TestScannerEllipsis(t *testing.T) {
+	s := New(strings.NewReader(`...a .5 a.b ..`))
+
+	expected := []token2.Type{
+		token2.Ellipsis,
+		token2.Ident,
+		token2.Number,
+		token2.Ident,
+		token2.Dot,
+		token2.Ident,
+		token2.Illegal,
+		token2.EOF,
+	}
+	for _, tt := range expected {
+		isToken(t, s.NextToken(), tt)
+	}
+}
@@ -0,0 +1,23 @@
+package token
+
+import "fmt"
+
+// Position is a location in the source. Offset counts bytes from the start
+// of the input; Line and Column start at 1 and Column counts runes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position is set.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
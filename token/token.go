@@ -40,19 +40,95 @@ const (
 	Null
 	True
 	False
+
+	Equal          // ==
+	NotEqual       // !=
+	StrictEqual    // ===
+	StrictNotEqual // !==
+	Less           // <
+	Greater        // >
+	LessEqual      // <=
+	GreaterEqual   // >=
+	Bang           // !
+
+	If
+	Else
+	Return
+	While
+	For
+	In
+	Break
+	Continue
+	Let
+	Const
+
+	Mod       // %
+	AddAssign // +=
+	SubAssign // -=
+	MulAssign // *=
+	DivAssign // /=
+	ModAssign // %=
+	Increment // ++
+	Decrement // --
+
+	This
+
+	Pow       // **
+	PowAssign // **=
+
+	Template       // `text`
+	TemplateHead   // `text${
+	TemplateMiddle // }text${
+	TemplateTail   // }text`
+
+	And           // &&
+	Or            // ||
+	Nullish       // ??
+	Question      // ?
+	OptionalChain // ?.
+	Typeof
+
+	Arrow    // =>
+	Ellipsis // ...
+
+	Throw
+	Try
+	Catch
+	Finally
+	NewKeyword // new
 )
 
 var Keywords = map[string]Type{
-	"var":   Var,
-	"fn":    Function,
-	"null":  Null,
-	"true":  True,
-	"false": False,
+	"var":      Var,
+	"fn":       Function,
+	"function": Function,
+	"null":     Null,
+	"true":     True,
+	"false":    False,
+	"if":       If,
+	"else":     Else,
+	"return":   Return,
+	"while":    While,
+	"for":      For,
+	"in":       In,
+	"break":    Break,
+	"continue": Continue,
+	"let":      Let,
+	"const":    Const,
+	"this":     This,
+	"typeof":   Typeof,
+	"throw":    Throw,
+	"try":      Try,
+	"catch":    Catch,
+	"finally":  Finally,
+	"new":      NewKeyword,
 }
 
 type Token struct {
 	Type  Type
 	Value string
+	Start Position // position of the first rune
+	End   Position // position just past the last rune
 }
 
 func New(tokenType Type, value string) *Token {
@@ -38,11 +38,58 @@ func _() {
 	_ = x[Null-28]
 	_ = x[True-29]
 	_ = x[False-30]
+	_ = x[Equal-31]
+	_ = x[NotEqual-32]
+	_ = x[StrictEqual-33]
+	_ = x[StrictNotEqual-34]
+	_ = x[Less-35]
+	_ = x[Greater-36]
+	_ = x[LessEqual-37]
+	_ = x[GreaterEqual-38]
+	_ = x[Bang-39]
+	_ = x[If-40]
+	_ = x[Else-41]
+	_ = x[Return-42]
+	_ = x[While-43]
+	_ = x[For-44]
+	_ = x[In-45]
+	_ = x[Break-46]
+	_ = x[Continue-47]
+	_ = x[Let-48]
+	_ = x[Const-49]
+	_ = x[Mod-50]
+	_ = x[AddAssign-51]
+	_ = x[SubAssign-52]
+	_ = x[MulAssign-53]
+	_ = x[DivAssign-54]
+	_ = x[ModAssign-55]
+	_ = x[Increment-56]
+	_ = x[Decrement-57]
+	_ = x[This-58]
+	_ = x[Pow-59]
+	_ = x[PowAssign-60]
+	_ = x[Template-61]
+	_ = x[TemplateHead-62]
+	_ = x[TemplateMiddle-63]
+	_ = x[TemplateTail-64]
+	_ = x[And-65]
+	_ = x[Or-66]
+	_ = x[Nullish-67]
+	_ = x[Question-68]
+	_ = x[OptionalChain-69]
+	_ = x[Typeof-70]
+	_ = x[Arrow-71]
+	_ = x[Ellipsis-72]
+	_ = x[Throw-73]
+	_ = x[Try-74]
+	_ = x[Catch-75]
+	_ = x[Finally-76]
+	_ = x[NewKeyword-77]
 }
 
-const _Type_name = "EOFIllegalAssignSemiDotCommaColonQuoteSQuoteIdentLiteralStringAddSubMulDivOpenParenCloseParenOpenBracketCloseBracketOpenCurlyCloseCurlyCommentLineCommentBlockVarNumberFunctionNullTrueFalse"
+const _Type_name = "EOFIllegalAssignSemiDotCommaColonQuoteSQuoteIdentLiteralStringAddSubMulDivOpenParenCloseParenOpenBracketCloseBracketOpenCurlyCloseCurlyCommentLineCommentBlockVarNumberFunctionNullTrueFalseEqualNotEqualStrictEqualStrictNotEqualLessGreaterLessEqualGreaterEqualBangIfElseReturnWhileForInBreakContinueLetConstModAddAssignSubAssignMulAssignDivAssignModAssignIncrementDecrementThisPowPowAssignTemplateTemplateHeadTemplateMiddleTemplateTailAndOrNullishQuestionOptionalChainTypeofArrowEllipsisThrowTryCatchFinallyNewKeyword"
 
-var _Type_index = [...]uint8{0, 3, 10, 16, 20, 23, 28, 33, 38, 44, 49, 56, 62, 65, 68, 71, 74, 83, 93, 104, 116, 125, 135, 146, 158, 161, 167, 175, 179, 183, 188}
+var _Type_index = [...]uint16{0, 3, 10, 16, 20, 23, 28, 33, 38, 44, 49, 56, 62, 65, 68, 71, 74, 83, 93, 104, 116, 125, 135, 146, 158, 161, 167, 175, 179, 183, 188, 193, 201, 212, 226, 230, 237, 246, 258, 262, 264, 268, 274, 279, 282, 284, 289, 297, 300, 305, 308, 317, 326, 335, 344, 353, 362, 371, 375, 378, 387, 395, 407, 421, 433, 436, 438, 445, 453, 466, 472, 477, 485, 490, 493, 498, 505, 515}
 
 func (i Type) String() string {
 	i -= 1
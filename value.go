@@ -0,0 +1,560 @@
+package js
+
+import (
+	"fmt"
+	"github.com/bundgaard/js/eval"
+	"github.com/bundgaard/js/object"
+	"math"
+	"reflect"
+	"strings"
+)
+
+var (
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// ToValue converts a Go value to the script value it stands for:
+//
+//	nil, nil pointers, maps and slices  null
+//	bool                                boolean
+//	ints, uints and floats              number
+//	string                              string
+//	slices and arrays                   array
+//	maps with string or number keys     object
+//	structs                             object of the exported fields
+//	funcs                               function
+//
+// Pointers and interfaces convert to the value they point to, and an
+// object.Object is returned as is. Struct fields are named by their js tag,
+// js:"name", or else by the field name; a field tagged js:"-" is left out.
+//
+// A func is called with its arguments converted by ExportTo. Its result, if
+// any, is converted by ToValue, and a non-nil error as its last result is
+// raised in the script.
+func ToValue(v interface{}) (object.Object, error) {
+	if obj, ok := v.(object.Object); ok {
+		return obj, nil
+	}
+	if fn, ok := v.(object.BuiltinFunction); ok {
+		return &object.BuiltinObject{Fn: fn}, nil
+	}
+	if fn, ok := v.(func(args ...object.Object) object.Object); ok {
+		return &object.BuiltinObject{Fn: fn}, nil
+	}
+	if v == nil {
+		return &object.NullObject{}, nil
+	}
+	return toValue(reflect.ValueOf(v), visits{})
+}
+
+// visits holds the values being converted, the path from the value passed
+// in down to the current one, to stop at a value that contains itself.
+type visits map[interface{}]bool
+
+// enter adds key to the path. It fails if key is on it already.
+func (path visits) enter(key interface{}, what string) error {
+	if path[key] {
+		return fmt.Errorf("cyclic %s", what)
+	}
+	path[key] = true
+	return nil
+}
+
+func (path visits) leave(key interface{}) {
+	delete(path, key)
+}
+
+// reference is how toValue tells pointers, maps and slices apart.
+type reference struct {
+	pointer uintptr
+	typ     reflect.Type
+}
+
+func toValue(v reflect.Value, path visits) (object.Object, error) {
+	if v.Type().Implements(objectType) && v.Kind() != reflect.Interface && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		return v.Interface().(object.Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return &object.Boolean{Value: v.Bool()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.NumberObject{Value: float64(v.Int())}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &object.NumberObject{Value: float64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.NumberObject{Value: v.Float()}, nil
+	case reflect.String:
+		return &object.StringObject{Value: v.String()}, nil
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return &object.NullObject{}, nil
+		}
+		return toValue(v.Elem(), path)
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return &object.NullObject{}, nil
+		}
+		key := reference{v.Pointer(), v.Type()}
+		if err := path.enter(key, v.Type().String()); err != nil {
+			return nil, err
+		}
+		defer path.leave(key)
+		return toValue(v.Elem(), path)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return &object.NullObject{}, nil
+			}
+			key := reference{v.Pointer(), v.Type()}
+			if err := path.enter(key, v.Type().String()); err != nil {
+				return nil, err
+			}
+			defer path.leave(key)
+		}
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			element, err := toValue(v.Index(i), path)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			elements[i] = element
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return &object.NullObject{}, nil
+		}
+		key := reference{v.Pointer(), v.Type()}
+		if err := path.enter(key, v.Type().String()); err != nil {
+			return nil, err
+		}
+		defer path.leave(key)
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, v.Len())}
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := toValue(iter.Key(), path)
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unsupported map key type %s", iter.Key().Type())
+			}
+			value, err := toValue(iter.Value(), path)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", key.Inspect(), err)
+			}
+			hash.Pairs[hashable.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+		return hash, nil
+
+	case reflect.Struct:
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+		for _, field := range structFields(v.Type()) {
+			value, err := toValue(v.FieldByIndex(field.index), path)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.name, err)
+			}
+			hash.SetProperty(field.name, value)
+		}
+		return hash, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return &object.NullObject{}, nil
+		}
+		return wrapFunc(v), nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %s to a script value", v.Type())
+}
+
+// wrapFunc returns a builtin that calls fn, converting the arguments and
+// results.
+func wrapFunc(fn reflect.Value) *object.BuiltinObject {
+	t := fn.Type()
+	return &object.BuiltinObject{
+		Fn: func(args ...object.Object) object.Object {
+			count := t.NumIn()
+			if t.IsVariadic() && len(args) > count {
+				count = len(args)
+			}
+
+			in := make([]reflect.Value, 0, count)
+			for i := 0; i < count; i++ {
+				var parameter reflect.Type
+				switch {
+				case t.IsVariadic() && i >= t.NumIn()-1:
+					if i >= len(args) {
+						continue
+					}
+					parameter = t.In(t.NumIn() - 1).Elem()
+				default:
+					parameter = t.In(i)
+				}
+
+				arg := reflect.New(parameter).Elem()
+				if i < len(args) {
+					if err := exportTo(args[i], arg, visits{}); err != nil {
+						return &object.Error{Name: "TypeError", Message: fmt.Sprintf("argument %d: %s", i+1, err)}
+					}
+				}
+				in = append(in, arg)
+			}
+
+			out := fn.Call(in)
+			if len(out) > 0 && t.Out(len(out)-1) == errorType {
+				if err, _ := out[len(out)-1].Interface().(error); err != nil {
+					return &object.Error{Message: err.Error()}
+				}
+				out = out[:len(out)-1]
+			}
+			if len(out) == 0 {
+				return &object.UndefinedObject{}
+			}
+
+			result, err := toValue(out[0], visits{})
+			if err != nil {
+				return &object.Error{Name: "TypeError", Message: err.Error()}
+			}
+			return result
+		},
+	}
+}
+
+// Export converts a script value to the Go value it stands for: nil for null
+// and undefined, bool, float64, string, []interface{} for arrays and
+// map[string]interface{} for objects. Errors export as *object.Error, and
+// functions as themselves. An array or object that contains itself cannot
+// be exported.
+func Export(obj object.Object) (interface{}, error) {
+	return export(obj, visits{})
+}
+
+func export(obj object.Object, path visits) (interface{}, error) {
+	switch v := object.Normalize(obj).(type) {
+	case nil, *object.NullObject, *object.UndefinedObject:
+		return nil, nil
+	case *object.Boolean:
+		return v.Value, nil
+	case *object.NumberObject:
+		return v.Value, nil
+	case *object.StringObject:
+		return v.Value, nil
+	case *object.Array:
+		if err := path.enter(v, "array"); err != nil {
+			return nil, err
+		}
+		defer path.leave(v)
+		elements := make([]interface{}, len(v.Elements))
+		for i, element := range v.Elements {
+			exported, err := export(element, path)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			elements[i] = exported
+		}
+		return elements, nil
+	case *object.Hash:
+		if err := path.enter(v, "object"); err != nil {
+			return nil, err
+		}
+		defer path.leave(v)
+		properties := make(map[string]interface{}, len(v.Pairs))
+		for _, pair := range v.Pairs {
+			exported, err := export(pair.Value, path)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+			}
+			properties[pair.Key.Inspect()] = exported
+		}
+		return properties, nil
+	default:
+		return obj, nil
+	}
+}
+
+// ExportTo converts obj and stores the result in the value target points to,
+// following the mapping of ToValue in reverse. Numbers stored in integer
+// types must be integers in range. A function stored in a func variable
+// calls the script function; see exportFunc.
+func ExportTo(obj object.Object, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("ExportTo: target must be a non-nil pointer, got %T", target)
+	}
+	return exportTo(obj, v.Elem(), visits{})
+}
+
+func exportTo(obj object.Object, v reflect.Value, path visits) error {
+	obj = object.Normalize(obj)
+	if obj == nil {
+		obj = &object.UndefinedObject{}
+	}
+
+	if v.Type() == objectType {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+	// Targets of a concrete object type, or of an interface such as
+	// object.PropertyGetter, receive the script value itself.
+	if t := reflect.TypeOf(obj); t.AssignableTo(v.Type()) && (v.Kind() != reflect.Interface || v.NumMethod() > 0) {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+
+	switch obj.(type) {
+	case *object.NullObject, *object.UndefinedObject:
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		exported, err := export(obj, path)
+		if err != nil {
+			return err
+		}
+		if exported == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if !reflect.TypeOf(exported).AssignableTo(v.Type()) {
+			return fmt.Errorf("cannot use %s as %s", typeName(obj), v.Type())
+		}
+		v.Set(reflect.ValueOf(exported))
+		return nil
+
+	case reflect.Bool:
+		b, ok := obj.(*object.Boolean)
+		if !ok {
+			return fmt.Errorf("cannot use %s as %s", typeName(obj), v.Type())
+		}
+		v.SetBool(b.Value)
+		return nil
+
+	case reflect.String:
+		s, ok := obj.(*object.StringObject)
+		if !ok {
+			return fmt.Errorf("cannot use %s as %s", typeName(obj), v.Type())
+		}
+		v.SetString(s.Value)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		n, ok := obj.(*object.NumberObject)
+		if !ok {
+			return fmt.Errorf("cannot use %s as %s", typeName(obj), v.Type())
+		}
+		return setNumber(n.Value, v)
+
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := exportTo(obj, elem.Elem(), path); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		array, ok := obj.(*object.Array)
+		if !ok {
+			return fmt.Errorf("cannot use %s as %s", typeName(obj), v.Type())
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(array.Elements), len(array.Elements)))
+		} else if len(array.Elements) > v.Len() {
+			return fmt.Errorf("array of %d elements does not fit in %s", len(array.Elements), v.Type())
+		}
+		if err := path.enter(array, "array"); err != nil {
+			return err
+		}
+		defer path.leave(array)
+		for i, element := range array.Elements {
+			if err := exportTo(element, v.Index(i), path); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		hash, ok := obj.(*object.Hash)
+		if !ok {
+			return fmt.Errorf("cannot use %s as %s", typeName(obj), v.Type())
+		}
+		if err := path.enter(hash, "object"); err != nil {
+			return err
+		}
+		defer path.leave(hash)
+		m := reflect.MakeMapWithSize(v.Type(), len(hash.Pairs))
+		for _, pair := range hash.Pairs {
+			key := reflect.New(v.Type().Key()).Elem()
+			if key.Kind() == reflect.String {
+				// Property names are strings to scripts, whatever the
+				// key was written as, as Export has them.
+				key.SetString(pair.Key.Inspect())
+			} else if err := exportTo(pair.Key, key, path); err != nil {
+				return fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := exportTo(pair.Value, value, path); err != nil {
+				return fmt.Errorf("key %s: %w", pair.Key.Inspect(), err)
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+		return nil
+
+	case reflect.Func:
+		switch obj.(type) {
+		case *object.Function, *object.Closure, *object.BuiltinObject:
+			v.Set(exportFunc(obj, v.Type()))
+			return nil
+		}
+		return fmt.Errorf("cannot use %s as %s", typeName(obj), v.Type())
+
+	case reflect.Struct:
+		getter, ok := obj.(object.PropertyGetter)
+		if !ok {
+			return fmt.Errorf("cannot use %s as %s", typeName(obj), v.Type())
+		}
+		if err := path.enter(obj, "object"); err != nil {
+			return err
+		}
+		defer path.leave(obj)
+		for _, field := range structFields(v.Type()) {
+			value, ok := getter.GetProperty(field.name)
+			if !ok {
+				continue
+			}
+			if err := exportTo(value, v.FieldByIndex(field.index), path); err != nil {
+				return fmt.Errorf("field %s: %w", field.name, err)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("cannot use %s as %s", typeName(obj), v.Type())
+}
+
+// exportFunc returns a Go func of type t that calls the script function fn.
+// The arguments are converted with ToValue and the result with ExportTo. A
+// script or conversion error is returned through the func's last result
+// when it is an error; otherwise the results are left as zero values.
+func exportFunc(fn object.Object, t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		out := make([]reflect.Value, t.NumOut())
+		for i := range out {
+			out[i] = reflect.New(t.Out(i)).Elem()
+		}
+		fail := func(err error) []reflect.Value {
+			if len(out) > 0 && t.Out(len(out)-1) == errorType {
+				out[len(out)-1] = reflect.ValueOf(&err).Elem()
+			}
+			return out
+		}
+
+		if t.IsVariadic() && len(in) > 0 {
+			last := in[len(in)-1]
+			in = in[:len(in)-1]
+			for i := 0; i < last.Len(); i++ {
+				in = append(in, last.Index(i))
+			}
+		}
+		args := make([]object.Object, len(in))
+		for i, arg := range in {
+			value, err := toValue(arg, visits{})
+			if err != nil {
+				return fail(fmt.Errorf("argument %d: %w", i+1, err))
+			}
+			args[i] = value
+		}
+
+		result, err := eval.Call(fn, nil, args...)
+		if err != nil {
+			return fail(err)
+		}
+		if len(out) > 0 && t.Out(0) != errorType {
+			if err := exportTo(result, out[0], visits{}); err != nil {
+				return fail(err)
+			}
+		}
+		return out
+	})
+}
+
+// typeName names the type of obj as scripts know it, for conversion errors.
+func typeName(obj object.Object) string {
+	switch obj.Type() {
+	case object.NullType:
+		return "null"
+	case object.UndefinedType:
+		return "undefined"
+	case object.NumberType:
+		return "number"
+	case object.StringType:
+		return "string"
+	case object.BooleanType:
+		return "boolean"
+	case object.ArrayType:
+		return "array"
+	case object.FunctionType, object.BuiltinType:
+		return "function"
+	}
+	return "object"
+}
+
+func setNumber(n float64, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(n)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n != math.Trunc(n) || v.OverflowInt(int64(n)) || n < math.MinInt64 || n >= math.MaxInt64 {
+			return fmt.Errorf("%s does not fit in %s", object.FormatNumber(n), v.Type())
+		}
+		v.SetInt(int64(n))
+		return nil
+	default:
+		if n != math.Trunc(n) || n < 0 || n >= math.MaxUint64 || v.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%s does not fit in %s", object.FormatNumber(n), v.Type())
+		}
+		v.SetUint(uint64(n))
+		return nil
+	}
+}
+
+type structField struct {
+	name  string
+	index []int
+}
+
+// structFields lists the exported fields of t with the names scripts see.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("js"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag = strings.Split(tag, ",")[0]; tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, structField{name: name, index: field.Index})
+	}
+	return fields
+}
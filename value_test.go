@@ -0,0 +1,318 @@
+package js
+
+import (
+	"context"
+	"errors"
+	"github.com/bundgaard/js/object"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City string `js:"city"`
+}
+
+type request struct {
+	Method  string            `js:"method"`
+	Path    string            `js:"path"`
+	Retries int               `js:"retries"`
+	Tags    []string          `js:"tags"`
+	Headers map[string]string `js:"headers"`
+	Address *address          `js:"address"`
+	Secret  string            `js:"-"`
+	Plain   bool
+	private int
+}
+
+func TestToValue(t *testing.T) {
+	tests := []struct {
+		Input    interface{}
+		Expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{1.5, "1.5"},
+		{"hi", "hi"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{[]int(nil), "null"},
+		{map[string]int{"a": 1}, "{a: 1}"},
+		{map[int]string{2: "b"}, "{2: b}"},
+		{(*address)(nil), "null"},
+		{&address{City: "Aarhus"}, "{city: Aarhus}"},
+		{&object.StringObject{Value: "as is"}, "as is"},
+	}
+
+	for idx, test := range tests {
+		value, err := ToValue(test.Input)
+		if err != nil {
+			t.Errorf("test[%04d] unexpected error %v", idx, err)
+			continue
+		}
+		if value.Inspect() != test.Expected {
+			t.Errorf("test[%04d] expected %q. got %q", idx, test.Expected, value.Inspect())
+		}
+	}
+
+	for _, input := range []interface{}{make(chan int), map[bool]int{true: 1}, []complex64{1}} {
+		if _, err := ToValue(input); err == nil {
+			t.Errorf("%T: expected an error", input)
+		}
+	}
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	r := NewRuntime()
+	in := request{
+		Method:  "GET",
+		Path:    "/users",
+		Retries: 2,
+		Tags:    []string{"a"},
+		Headers: map[string]string{"accept": "json"},
+		Address: &address{City: "Aarhus"},
+		Secret:  "hidden",
+		Plain:   true,
+	}
+	if err := r.Set("req", in); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	result, err := r.RunString("`${req.secret} ${req.Secret} ${req.Plain}`")
+	if err != nil || result.Inspect() != "undefined undefined true" {
+		t.Errorf("expected hidden fields to be left out. got %v, %v", result, err)
+	}
+
+	result, err = r.RunString(`
+var res = {
+  method: req.method,
+  path: req.path + "/1",
+  retries: req.retries + 1,
+  tags: [...req.tags, "b"],
+  headers: {accept: req.headers.accept, "x-city": req.address.city},
+  address: {city: "Odense"},
+};
+res
+`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var out request
+	if err := ExportTo(result, &out); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := request{
+		Method:  "GET",
+		Path:    "/users/1",
+		Retries: 3,
+		Tags:    []string{"a", "b"},
+		Headers: map[string]string{"accept": "json", "x-city": "Aarhus"},
+		Address: &address{City: "Odense"},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected %+v. got %+v", expected, out)
+	}
+
+	keyed, err := r.RunString(`({1: "x", a: "y"})`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var names map[string]interface{}
+	if err := ExportTo(keyed, &names); err != nil || !reflect.DeepEqual(names, map[string]interface{}{"1": "x", "a": "y"}) {
+		t.Errorf("expected number keys as strings. got %v, %v", names, err)
+	}
+
+	value, err := Export(result)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	exported := value.(map[string]interface{})
+	if exported["retries"] != 3.0 || exported["tags"].([]interface{})[1] != "b" {
+		t.Errorf("unexpected export %v", exported)
+	}
+}
+
+func TestExportToErrors(t *testing.T) {
+	var n int
+	if err := ExportTo(&object.NumberObject{Value: 1.5}, &n); err == nil {
+		t.Errorf("expected an error storing 1.5 in an int")
+	}
+	var b int8
+	if err := ExportTo(&object.NumberObject{Value: 300}, &b); err == nil {
+		t.Errorf("expected an error storing 300 in an int8")
+	}
+	var s string
+	if err := ExportTo(&object.NumberObject{Value: 1}, &s); err == nil || err.Error() != "cannot use number as string" {
+		t.Errorf("expected an error storing a number in a string. got %v", err)
+	}
+	var f func()
+	if err := ExportTo(&object.StringObject{Value: "f"}, &f); err == nil || err.Error() != "cannot use string as func()" {
+		t.Errorf("expected an error storing a string in a func. got %v", err)
+	}
+	if err := ExportTo(&object.NumberObject{Value: 1}, s); err == nil {
+		t.Errorf("expected an error for a target that is not a pointer")
+	}
+
+	var getter object.PropertyGetter
+	hash := &object.Hash{Pairs: map[object.HashKey]object.HashPair{}}
+	if err := ExportTo(hash, &getter); err != nil || getter != hash {
+		t.Errorf("expected the hash itself. got %v, %v", getter, err)
+	}
+}
+
+func TestGoFunctions(t *testing.T) {
+	r := NewRuntime()
+	r.Set("upper", strings.ToUpper)
+	r.Set("sum", func(xs ...int) int {
+		total := 0
+		for _, x := range xs {
+			total += x
+		}
+		return total
+	})
+	r.Set("lookup", func(id int) (*address, error) {
+		if id != 1 {
+			return nil, errors.New("not found")
+		}
+		return &address{City: "Aarhus"}, nil
+	})
+
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`upper("abc")`, "ABC"},
+		{`sum(1, 2, 3)`, "6"},
+		{`sum()`, "0"},
+		{`lookup(1).city`, "Aarhus"},
+		{`var r = null; try { lookup(2); } catch (e) { r = e.message; } r`, "not found"},
+		{`var r = null; try { upper(1); } catch (e) { r = e.name; } r`, "TypeError"},
+	}
+
+	for idx, test := range tests {
+		result, err := r.RunString(test.Input)
+		if err != nil {
+			t.Errorf("test[%04d] %s: unexpected error %v", idx, test.Input, err)
+			continue
+		}
+		if result.Inspect() != test.Expected {
+			t.Errorf("test[%04d] %s: expected %q. got %q", idx, test.Input, test.Expected, result.Inspect())
+		}
+	}
+}
+
+func TestExportFunc(t *testing.T) {
+	r := NewRuntime()
+	result, err := r.RunString(`fn(a, b) { if (b == 0) { throw new RangeError("division by zero"); } return a / b; }`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var divide func(a, b float64) (float64, error)
+	if err := ExportTo(result, &divide); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if q, err := divide(9, 3); err != nil || q != 3 {
+		t.Errorf("expected 3. got %v, %v", q, err)
+	}
+	if _, err := divide(1, 0); err == nil || !strings.Contains(err.Error(), "RangeError: division by zero") {
+		t.Errorf("expected the script error. got %v", err)
+	}
+
+	result, err = r.RunString(`(...xs) => xs.length`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var count func(xs ...string) int
+	if err := ExportTo(result, &count); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if n := count("a", "b", "c"); n != 3 {
+		t.Errorf("expected 3. got %d", n)
+	}
+}
+
+func TestExportFuncBudget(t *testing.T) {
+	r := NewRuntime()
+	r.Budget().MaxSteps = 1000
+	ctx, cancel := context.WithCancel(context.Background())
+	result, err := r.RunContext(ctx, `fn(a, b) { return a + b; }`)
+	cancel()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var add func(a, b float64) (float64, error)
+	if err := ExportTo(result, &add); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// Every call has a budget of its own, without the context of the run
+	// that created the function.
+	for i := 0; i < 1000; i++ {
+		if n, err := add(1, 2); err != nil || n != 3 {
+			t.Fatalf("call %d: expected 3. got %v, %v", i, n, err)
+		}
+	}
+
+	result, err = r.RunString(`fn() { while (true) {} }`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var spin func() error
+	if err := ExportTo(result, &spin); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := spin(); err == nil || !strings.Contains(err.Error(), "step limit of 1000 exceeded") {
+		t.Errorf("expected the step limit. got %v", err)
+	}
+}
+
+func TestCyclicValues(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	loop := &node{Name: "a"}
+	loop.Next = &node{Name: "b", Next: loop}
+	list := []interface{}{1, nil}
+	list[1] = list
+	m := map[string]interface{}{}
+	m["self"] = m
+
+	for _, v := range []interface{}{loop, list, m} {
+		if _, err := ToValue(v); err == nil || !strings.Contains(err.Error(), "cyclic") {
+			t.Errorf("expected a cyclic value error for %T. got %v", v, err)
+		}
+	}
+	if err := NewRuntime().Set("loop", loop); err == nil {
+		t.Errorf("expected Set to fail on a cyclic value")
+	}
+
+	// A value seen twice without a cycle converts.
+	shared := &node{Name: "shared"}
+	if _, err := ToValue([]*node{shared, shared}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	result, err := Run(`var o = {Name: "o"}; o.Next = o; var a = [1]; a[1] = a; [o, a]`, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cycles := result.(*object.Array).Elements
+	for _, obj := range cycles {
+		if _, err := Export(obj); err == nil || !strings.Contains(err.Error(), "cyclic") {
+			t.Errorf("expected a cyclic value error exporting %s. got %v", obj.Type(), err)
+		}
+	}
+	var target interface{}
+	if err := ExportTo(cycles[0], &target); err == nil {
+		t.Errorf("expected ExportTo to fail on a cyclic object")
+	}
+	var n node
+	if err := ExportTo(cycles[0], &n); err == nil {
+		t.Errorf("expected ExportTo to fail on a cyclic object")
+	}
+}
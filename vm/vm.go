@@ -0,0 +1,618 @@
+// Package vm runs the bytecode produced by package compiler.
+//
+// The virtual machine shares the object package and the operators of
+// package eval with the tree-walking interpreter, so a compiled program
+// gives the same results and errors as eval.Run gives for its source, only
+// faster.
+package vm
+
+import (
+	"github.com/bundgaard/js/code"
+	"github.com/bundgaard/js/eval"
+	"github.com/bundgaard/js/object"
+	"github.com/bundgaard/js/token"
+	"runtime/debug"
+)
+
+// Run runs program, as compiled by compiler.Compile, in environment. Like
+// eval.Run, it returns an uncaught script error as the *object.Error,
+// recovers panics as an *eval.PanicError and runs in the scope
+// eval.RunEnvironment returns for environment.
+func Run(program *object.CompiledFunction, environment *object.Environment) (result object.Object, err error) {
+	environment = eval.RunEnvironment(environment)
+	vm := &VM{budget: environment.Budget()}
+	defer vm.recoverPanic(&result, &err)
+
+	vm.frames = append(vm.frames, &frame{fn: program, env: environment})
+	return vm.run()
+}
+
+func init() {
+	eval.CallClosure = Call
+}
+
+// Call calls closure, held to budget, with this bound to this and the given
+// arguments. It is how package eval, and so a Go host, calls the functions
+// of a compiled program. Unlike Run it returns an uncaught error as the
+// result and does not recover panics, as for a call made by eval.
+func Call(closure *object.Closure, this object.Object, args []object.Object, budget *object.Budget) object.Object {
+	if budget == nil {
+		budget = object.NewBudget()
+	}
+	if err := budget.Enter(); err != nil {
+		return err
+	}
+	defer budget.Leave()
+
+	vm := &VM{budget: budget}
+	defer vm.repanic()
+	vm.frames = append(vm.frames, vm.newFrame(closure, this, args, nil))
+	result, err := vm.run()
+	if err != nil {
+		return err.(*object.Error)
+	}
+	return result
+}
+
+// VM is a stack machine. Calls between compiled functions push frames
+// instead of recursing on the Go stack.
+type VM struct {
+	stack  []object.Object
+	frames []*frame
+	budget *object.Budget
+}
+
+type frame struct {
+	fn *object.CompiledFunction
+	// closure is nil for the program.
+	closure *object.Closure
+	// ip is the offset of the next instruction and start that of the one
+	// being run.
+	ip, start int
+	env       *object.Environment
+	// args are the arguments of the call, for OpArgument.
+	args []object.Object
+	// base is the height of the stack below the frame, where its result
+	// goes.
+	base       int
+	completion object.Object
+	// construct is the object a constructor call creates.
+	construct *object.Hash
+	handlers  []handler
+	// closures is set once the frame has created a closure. Until then no
+	// closure can keep a scope alive, so OpCopyScope need not copy it.
+	closures bool
+}
+
+// handler is a catch or finally block installed by OpTry.
+type handler struct {
+	target int
+	height int
+	env    *object.Environment
+}
+
+// spread is a value pushed by OpSpread, whose elements the instruction
+// that collects it expands.
+type spread struct {
+	values []object.Object
+}
+
+func (s *spread) Type() object.Type { return object.ArrayType }
+func (s *spread) Inspect() string   { return "..." }
+
+// iterator is the state of a for...in or for...of loop.
+type iterator struct {
+	next func(i int) (object.Object, bool)
+	i    int
+}
+
+func (it *iterator) Type() object.Type { return object.ArrayType }
+func (it *iterator) Inspect() string   { return "iterator" }
+
+func (vm *VM) push(obj object.Object) {
+	vm.stack = append(vm.stack, obj)
+}
+
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return obj
+}
+
+func (vm *VM) peek() object.Object {
+	return vm.stack[len(vm.stack)-1]
+}
+
+// popN pops n values, expanding spread values, and returns them in the
+// order they were pushed.
+func (vm *VM) popN(n int) []object.Object {
+	values := vm.stack[len(vm.stack)-n:]
+	vm.stack = vm.stack[:len(vm.stack)-n]
+
+	result := make([]object.Object, 0, n)
+	for _, value := range values {
+		if s, ok := value.(*spread); ok {
+			result = append(result, s.values...)
+			continue
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+// recoverPanic turns a panic into an *eval.PanicError at the position of
+// the instruction being run.
+func (vm *VM) recoverPanic(result *object.Object, err *error) {
+	if r := recover(); r != nil {
+		*result, *err = nil, vm.panicError(r)
+	}
+}
+
+// repanic panics again with the *eval.PanicError for a panic, for the run
+// that called into the virtual machine to recover. It must be deferred.
+func (vm *VM) repanic() {
+	if r := recover(); r != nil {
+		panic(vm.panicError(r))
+	}
+}
+
+func (vm *VM) panicError(r interface{}) *eval.PanicError {
+	perr, ok := r.(*eval.PanicError)
+	if !ok {
+		perr = &eval.PanicError{Value: r, Stack: debug.Stack()}
+		if len(vm.frames) > 0 {
+			f := vm.frames[len(vm.frames)-1]
+			perr.Position = f.fn.Positions.At(f.start)
+		}
+	}
+	return perr
+}
+
+// throw hands err to the innermost handler. Frames without one are left,
+// recording the calls in the stack trace of err. It reports whether a
+// handler was found; if not, err ends the run.
+func (vm *VM) throw(err *object.Error) bool {
+	f := vm.frames[len(vm.frames)-1]
+	if !err.Position.IsValid() {
+		err.Position = f.fn.Positions.At(f.start)
+	}
+	for {
+		f := vm.frames[len(vm.frames)-1]
+		if n := len(f.handlers); n > 0 && !err.Uncatchable {
+			h := f.handlers[n-1]
+			f.handlers = f.handlers[:n-1]
+			vm.stack = vm.stack[:h.height]
+			vm.push(err)
+			f.env = h.env
+			f.ip = h.target
+			return true
+		}
+		if len(vm.frames) == 1 {
+			// The bottom frame, the program or a closure called by Call,
+			// ends the run.
+			return false
+		}
+		vm.frames = vm.frames[:len(vm.frames)-1]
+		vm.stack = vm.stack[:f.base]
+		vm.budget.Leave()
+		caller := vm.frames[len(vm.frames)-1]
+		err.Unwind(name(f.fn), caller.fn.Calls.At(caller.start))
+	}
+}
+
+func name(fn *object.CompiledFunction) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+	return fn.Name
+}
+
+// call calls fn with the arguments on top of the stack. A compiled function
+// gets a new frame; anything else is applied by package eval.
+func (vm *VM) call(fn, this object.Object, args []object.Object, construct *object.Hash) object.Object {
+	f := vm.frames[len(vm.frames)-1]
+	closure, ok := fn.(*object.Closure)
+	if !ok {
+		result := eval.Apply(fn, this, args, f.env, f.fn.Calls.At(f.start))
+		return vm.result(result)
+	}
+
+	if err := vm.budget.Enter(); err != nil {
+		// As in eval, the error happened at the call.
+		err.Position = f.fn.Calls.At(f.start)
+		return err
+	}
+	vm.frames = append(vm.frames, vm.newFrame(closure, this, args, construct))
+	return nil
+}
+
+// newFrame binds the arguments of a call to closure in a new scope and
+// returns the frame to run it in.
+func (vm *VM) newFrame(closure *object.Closure, this object.Object, args []object.Object, construct *object.Hash) *frame {
+	compiled := closure.Function
+	env := object.NewEnclosedEnvironment(closure.Environment)
+	// As in eval, the call is held to the budget of the run making it.
+	env.SetBudget(vm.budget)
+	if compiled.UsesArguments {
+		env.Set("arguments", &object.Array{Elements: append([]object.Object{}, args...)})
+	}
+	for idx, param := range compiled.Parameters[:compiled.Bound] {
+		var value object.Object = &object.UndefinedObject{}
+		if idx < len(args) {
+			value = args[idx]
+		}
+		env.Set(param, value)
+	}
+	if compiled.Rest != "" {
+		rest := &object.Array{Elements: []object.Object{}}
+		if len(args) > len(compiled.Parameters) {
+			rest.Elements = append(rest.Elements, args[len(compiled.Parameters):]...)
+		}
+		env.Set(compiled.Rest, rest)
+	}
+	if compiled.UsesThis {
+		// A plain call has no receiver; bind this anyway so it does not
+		// resolve to the this of an enclosing function.
+		if this == nil {
+			this = &object.NullObject{}
+		}
+		env.Set("this", this)
+	}
+
+	return &frame{
+		fn:        compiled,
+		closure:   closure,
+		env:       env,
+		args:      args,
+		base:      len(vm.stack),
+		construct: construct,
+	}
+}
+
+func (vm *VM) run() (object.Object, error) {
+	f := vm.frames[len(vm.frames)-1]
+	for {
+		ins := f.fn.Instructions
+		f.start = f.ip
+		op := code.Opcode(ins[f.ip])
+		f.ip++
+
+		var result object.Object
+		if err := vm.budget.Step(); err != nil {
+			result = err
+		} else {
+			result = vm.execute(f, op, ins)
+		}
+
+		if err, ok := result.(*object.Error); ok {
+			if !vm.throw(err) {
+				return nil, err
+			}
+		} else if result == returned {
+			// The frame returned. The bottom frame ends the run.
+			value := vm.pop()
+			if value == nil && f.closure != nil {
+				// As in eval, a function that ends without a value
+				// returns undefined.
+				value = &object.UndefinedObject{}
+			}
+			if len(vm.frames) == 1 {
+				return value, nil
+			}
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.stack = vm.stack[:f.base]
+			vm.budget.Leave()
+			if f.construct != nil {
+				if _, ok := value.(*object.Hash); !ok {
+					value = f.construct
+				}
+			}
+			vm.push(value)
+		}
+		f = vm.frames[len(vm.frames)-1]
+	}
+}
+
+// returned is what execute returns for OpReturn.
+var returned object.Object = &object.ReturnValue{}
+
+// execute runs the instruction op of frame f, whose operands start at f.ip.
+// It returns an error raised by the instruction, or returned.
+func (vm *VM) execute(f *frame, op code.Opcode, ins code.Instructions) object.Object {
+	switch op {
+	case code.OpConstant:
+		vm.push(f.fn.Constants[vm.operand(f, ins)])
+	case code.OpNil:
+		vm.push(nil)
+	case code.OpNull:
+		vm.push(&object.NullObject{})
+	case code.OpUndefined:
+		vm.push(&object.UndefinedObject{})
+	case code.OpTrue:
+		vm.push(&object.Boolean{Value: true})
+	case code.OpFalse:
+		vm.push(&object.Boolean{Value: false})
+
+	case code.OpPop:
+		vm.pop()
+	case code.OpComplete:
+		f.completion = vm.pop()
+	case code.OpCompletion:
+		vm.push(f.completion)
+	case code.OpDup:
+		vm.push(vm.peek())
+	case code.OpDup2:
+		n := len(vm.stack)
+		vm.push(vm.stack[n-2])
+		vm.push(vm.stack[n-1])
+
+	case code.OpBinary:
+		operator := code.Operators[vm.operand8(f, ins)]
+		right := vm.pop()
+		left := vm.pop()
+		return vm.result(eval.Infix(operator, left, right, f.env))
+	case code.OpPrefix:
+		operator := code.Operators[vm.operand8(f, ins)]
+		return vm.result(eval.Prefix(operator, vm.pop()))
+
+	case code.OpGetName:
+		return vm.result(eval.Identifier(vm.name(f, ins), f.env))
+	case code.OpTypeofName:
+		vm.push(eval.Typeof(vm.name(f, ins), f.env))
+	case code.OpAssignName:
+		name := vm.name(f, ins)
+		if err := eval.Assign(name, vm.peek(), f.env); isError(err) {
+			return err
+		}
+	case code.OpDefine:
+		f.env.Set(vm.name(f, ins), vm.peek())
+	case code.OpDeclare:
+		name := vm.name(f, ins)
+		kind := vm.operand8(f, ins)
+		if err := eval.Declare(token.Type(kind), name, vm.pop(), f.env); err != nil {
+			return err
+		}
+	case code.OpArgument:
+		if idx := vm.operand(f, ins); idx < len(f.args) {
+			vm.push(f.args[idx])
+		} else {
+			vm.push(&object.UndefinedObject{})
+		}
+	case code.OpThis:
+		if this, ok := f.env.Get("this"); ok {
+			vm.push(this)
+		} else {
+			vm.push(&object.NullObject{})
+		}
+
+	case code.OpPushScope:
+		f.env = object.NewBlockEnvironment(f.env)
+	case code.OpPopScope:
+		f.env = f.env.Outer
+	case code.OpCopyScope:
+		if f.closures {
+			f.env = f.env.Copy()
+		}
+
+	case code.OpJump:
+		f.ip = vm.operand(f, ins)
+	case code.OpJumpIfFalse:
+		target := vm.operand(f, ins)
+		if !eval.Truthy(vm.pop()) {
+			f.ip = target
+		}
+	case code.OpJumpIfFalseKeep:
+		vm.jumpKeep(f, ins, !eval.Truthy(vm.peek()))
+	case code.OpJumpIfTrueKeep:
+		vm.jumpKeep(f, ins, eval.Truthy(vm.peek()))
+	case code.OpJumpIfNotNullishKeep:
+		vm.jumpKeep(f, ins, !eval.Nullish(vm.peek()))
+	case code.OpJumpIfNotUndefined:
+		target := vm.operand(f, ins)
+		if value := vm.pop(); value != nil && value.Type() != object.UndefinedType {
+			f.ip = target
+		}
+	case code.OpJumpIfNullish:
+		target := vm.operand(f, ins)
+		drop := vm.operand8(f, ins)
+		if eval.Nullish(vm.peek()) {
+			vm.stack = vm.stack[:len(vm.stack)-1-drop]
+			vm.push(&object.UndefinedObject{})
+			f.ip = target
+		}
+
+	case code.OpArray:
+		return vm.result(eval.NewArray(vm.popN(vm.operand(f, ins)), f.env))
+	case code.OpHash:
+		n := vm.operand(f, ins)
+		entries := vm.stack[len(vm.stack)-2*n:]
+		vm.stack = vm.stack[:len(vm.stack)-2*n]
+		keys := make([]object.Object, n)
+		values := make([]object.Object, n)
+		for i := 0; i < n; i++ {
+			keys[i], values[i] = entries[2*i], entries[2*i+1]
+		}
+		return vm.result(eval.NewHash(keys, values, f.env))
+	case code.OpTemplate:
+		return vm.result(eval.Template(vm.popN(vm.operand(f, ins)), f.env))
+	case code.OpSpread:
+		value := vm.pop()
+		next := eval.Values(value)
+		if next == nil {
+			return eval.NotIterable(value)
+		}
+		s := &spread{}
+		for i := 0; ; i++ {
+			v, ok := next(i)
+			if !ok {
+				break
+			}
+			s.values = append(s.values, v)
+		}
+		vm.push(s)
+
+	case code.OpIndex:
+		index := vm.pop()
+		left := vm.pop()
+		return vm.result(eval.Index(left, index))
+	case code.OpMember:
+		return vm.result(eval.GetProperty(vm.pop(), vm.name(f, ins)))
+	case code.OpIndexRef:
+		index := vm.pop()
+		left := vm.pop()
+		return vm.result(eval.IndexReference(left, index, f.env))
+	case code.OpMemberRef:
+		return vm.result(eval.PropertyReference(vm.pop(), vm.name(f, ins)))
+	case code.OpSetIndex:
+		value := vm.pop()
+		index := vm.pop()
+		left := vm.pop()
+		return vm.result(eval.SetIndex(left, index, value, f.env))
+	case code.OpSetMember:
+		name := vm.name(f, ins)
+		value := vm.pop()
+		return vm.result(eval.SetProperty(vm.pop(), name, value))
+	case code.OpUpdateName:
+		name := vm.name(f, ins)
+		operator, prefix := update(vm.operand8(f, ins))
+		return vm.result(eval.UpdateName(operator, prefix, name, f.env))
+	case code.OpUpdateIndex:
+		operator, prefix := update(vm.operand8(f, ins))
+		index := vm.pop()
+		left := vm.pop()
+		return vm.result(eval.UpdateIndex(operator, prefix, left, index, f.env))
+	case code.OpUpdateMember:
+		name := vm.name(f, ins)
+		operator, prefix := update(vm.operand8(f, ins))
+		return vm.result(eval.UpdateProperty(operator, prefix, vm.pop(), name))
+
+	case code.OpCall:
+		args := vm.popN(vm.operand8(f, ins))
+		return vm.call(vm.pop(), nil, args, nil)
+	case code.OpCallMethod:
+		args := vm.popN(vm.operand8(f, ins))
+		fn := vm.pop()
+		return vm.call(fn, vm.pop(), args, nil)
+	case code.OpNew:
+		name := vm.name(f, ins)
+		args := vm.popN(vm.operand8(f, ins))
+		callee := vm.pop()
+		if closure, ok := callee.(*object.Closure); ok {
+			if closure.Function.Arrow {
+				return eval.Construct(nil, name, args, f.env, f.fn.Calls.At(f.start))
+			}
+			this := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+			return vm.call(closure, this, args, this)
+		}
+		return vm.result(eval.Construct(callee, name, args, f.env, f.fn.Calls.At(f.start)))
+	case code.OpClosure:
+		fn := f.fn.Constants[vm.operand(f, ins)].(*object.CompiledFunction)
+		f.closures = true
+		if vm.operand8(f, ins) == 0 {
+			vm.push(&object.Closure{Function: fn, Environment: f.env})
+			break
+		}
+		scope := object.NewEnclosedEnvironment(f.env)
+		closure := &object.Closure{Function: fn, Environment: scope}
+		scope.Set(fn.Name, closure)
+		vm.push(closure)
+	case code.OpReturn:
+		return returned
+	case code.OpThrow:
+		value := vm.pop()
+		if err, ok := value.(*object.Error); ok {
+			return err
+		}
+		return eval.Throw(value)
+
+	case code.OpTry:
+		f.handlers = append(f.handlers, handler{
+			target: vm.operand(f, ins),
+			height: len(vm.stack),
+			env:    f.env,
+		})
+	case code.OpEndTry:
+		f.handlers = f.handlers[:len(f.handlers)-1]
+	case code.OpCatch:
+		vm.push(eval.Catch(vm.pop().(*object.Error)))
+	case code.OpIter:
+		of := vm.operand8(f, ins) == 1
+		iterable := vm.pop()
+		next := eval.Keys(iterable)
+		if of {
+			next = eval.Values(iterable)
+			if next == nil {
+				return eval.NotIterable(iterable)
+			}
+		}
+		vm.push(&iterator{next: next})
+	case code.OpIterNext:
+		target := vm.operand(f, ins)
+		it := vm.peek().(*iterator)
+		value, ok := it.next(it.i)
+		if !ok {
+			f.ip = target
+			break
+		}
+		it.i++
+		vm.push(value)
+
+	default:
+		def, _ := code.Lookup(byte(op))
+		panic("vm: unknown instruction " + def.Name)
+	}
+	return nil
+}
+
+// result pushes the result of an operation unless it is an error, which
+// it returns.
+func (vm *VM) result(obj object.Object) object.Object {
+	if isError(obj) {
+		return obj
+	}
+	vm.push(obj)
+	return nil
+}
+
+func isError(obj object.Object) bool {
+	_, ok := obj.(*object.Error)
+	return ok
+}
+
+// jumpKeep performs a Keep jump: it jumps with the value on the stack or
+// pops it and goes on.
+func (vm *VM) jumpKeep(f *frame, ins code.Instructions, jump bool) {
+	target := vm.operand(f, ins)
+	if jump {
+		f.ip = target
+		return
+	}
+	vm.pop()
+}
+
+// operand reads a two byte operand.
+func (vm *VM) operand(f *frame, ins code.Instructions) int {
+	v := int(code.ReadUint16(ins[f.ip:]))
+	f.ip += 2
+	return v
+}
+
+// operand8 reads a one byte operand.
+func (vm *VM) operand8(f *frame, ins code.Instructions) int {
+	v := int(code.ReadUint8(ins[f.ip:]))
+	f.ip++
+	return v
+}
+
+// name reads an operand naming a string constant.
+func (vm *VM) name(f *frame, ins code.Instructions) string {
+	return f.fn.Constants[vm.operand(f, ins)].(*object.StringObject).Value
+}
+
+func update(flags int) (operator string, prefix bool) {
+	operator = "++"
+	if flags&code.UpdateDecrement != 0 {
+		operator = "--"
+	}
+	return operator, flags&code.UpdatePrefix != 0
+}
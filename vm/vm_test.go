@@ -0,0 +1,382 @@
+package vm
+
+import (
+	"github.com/bundgaard/js/ast"
+	"github.com/bundgaard/js/compiler"
+	"github.com/bundgaard/js/eval"
+	"github.com/bundgaard/js/object"
+	"github.com/bundgaard/js/parser"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// differentialTests are run by both engines, which have to agree on the
+// result or the error.
+var differentialTests = []string{
+	// expressions
+	`1 + 2 * 3 - 4 / 2`,
+	`2 ** 10 % 7`,
+	`"a" + "b"`,
+	`-"3" + +true`,
+	`!0 === true`,
+	`1 == "1"`,
+	`null ?? "default"`,
+	`0 || "right"`,
+	`1 && 0`,
+	`typeof undeclared`,
+	`typeof typeof 1`,
+	`typeof function() {}`,
+	`1 < 2 ? "yes" : "no"`,
+	"`sum ${1 + 2} and ${[1, 2]}`",
+	`[1, "two", [3], {a: 1}]`,
+	`({b: 2, a: 1, c: {d: [1]}})`,
+	`undefined`,
+	`NaN`,
+
+	// statements and completion values
+	``,
+	`var a = 1;`,
+	`var a = 1; a;`,
+	`if (false) { 1 }`,
+	`if (true) { 1 } else { 2 }`,
+	`if (false) { 1 } else if (true) { 2 } else { 3 }`,
+	`1; if (true) {}`,
+	`{ 5 }`,
+	`function f() {}`,
+	`let i = 0; while (i < 3) { i++; }`,
+	`let i = 0; while (i < 3) { i++; } i`,
+
+	// variables and scopes
+	`let x = 1; { let x = 2; } x`,
+	`var x = 1; { var x = 2; } x`,
+	`const x = 1; x = 2;`,
+	`let x = 1; let x = 2;`,
+	`x = 1;`,
+	`missing`,
+	`let x = 1; { x = 2; } x`,
+
+	// assignment and update
+	`var a = [1, 2]; a[3] = 4; a`,
+	`var a = [1]; a[0] += 5; a[0]`,
+	`var o = {}; o.n = 1; o.n *= 10; o`,
+	`var o = {n: 1}; o.n++ + ++o.n`,
+	`var a = [5]; a[0]--; --a[0]`,
+	`var i = 1; [i++, i, ++i, i--, i]`,
+	`var s = "a"; s += "b"; s`,
+	`null.x = 1;`,
+	`var n = null; n.x += 1;`,
+	`var a = "s"; a++;`,
+	`var o = {}; o[{}] = 1;`,
+
+	// loops
+	`var s = 0; for (var i = 0; i < 10; i++) { if (i == 5) { break; } s += i; } s`,
+	`var s = 0; for (let i = 0; i < 10; i++) { if (i % 2) { continue; } s += i; } s`,
+	`var fns = []; for (let i = 0; i < 3; i++) { fns[i] = function() { return i; }; } [fns[0](), fns[1](), fns[2]()]`,
+	`var fns = []; for (let i = 0; i < 2; i++) { for (let j = 0; j < 2; j++) { fns[fns.length] = () => i * 10 + j; } } [fns[0](), fns[1](), fns[2](), fns[3]()]`,
+	`var s = 0; for (;;) { s++; if (s > 4) { break; } } s`,
+	`var keys = []; for (let k in {b: 1, a: 2}) { keys[keys.length] = k; } keys`,
+	`var out = ""; for (const c of "abc") { out += c; } out`,
+	`var out = 0; for (var v of [1, 2, 3]) { out += v; } [out, v]`,
+	`var s = 0; for (var v of [1, 2, 3, 4]) { if (v == 2) { continue; } if (v == 4) { break; } s += v; } s`,
+	`var n = 0; for (let a of [1, 2]) { for (let b of [1, 2]) { if (b == 2) { break; } n += a * b; } } n`,
+	`for (var x of 5) {}`,
+	`var k; for (k in [7, 8]) {} k`,
+
+	// functions
+	`function add(a, b) { return a + b; } add(1, 2)`,
+	`function f(a, b) { return b; } f(1)`,
+	`function f() { 42 } f()`,
+	`function f() { return; } f()`,
+	`function f(a, b = a + 1) { return [a, b]; } f(1)`,
+	`function f(a = 5) { return a; } f(undefined)`,
+	`function f(first, ...rest) { return [first, rest]; } f(1, 2, 3)`,
+	`function f() { return arguments; } f(1, "two")`,
+	`function f() { return (() => arguments)(); } f(1, 2)`,
+	`var o = {v: 1, f: function() { var g = () => () => this.v; return g()(); }}; o.f()`,
+	`var arguments = 1; function f() { return arguments; } [f(2), arguments]`,
+	`var f = (a, b) => a * b; f(3, 4)`,
+	`var add = x => y => x + y; add(1)(2)`,
+	`function max(...xs) { var m = xs[0]; for (var x of xs) { if (x > m) { m = x; } } return m; } max(...[3, 9], 4)`,
+	`var fact = function me(n) { if (n < 2) { return 1; } return n * me(n - 1); }; fact(5)`,
+	`var f = function g() {}; typeof g`,
+	`function counter() { var n = 0; return function() { n++; return n; }; } var c = counter(); c(); c()`,
+	`function f() { return f; } f() === f`,
+	`function f(x) { if (x) { let y = 2; return y; } return 3; } [f(true), f(false)]`,
+	`function outer() { function inner() { return 1; } return inner() + 1; } outer()`,
+	`(function() { return 1; })`,
+	`(x, y = 2, ...z) => x`,
+	`var o = {n: 1, get: function() { return this.n; }}; o.get()`,
+	`var o = {n: 1, m: {n: 2, get: function() { return this.n; }}}; o.m.get() + o["m"].get()`,
+	`function f() { return this; } f()`,
+	`var o = {n: 1, f: function() { return () => this.n; }}; o.f()()`,
+	`len("hello")`,
+	`5()`,
+	`var o = {}; o.missing()`,
+	`len(1)`,
+	`function f() { return missing; } f()`,
+	`function a() { return b(); } function b() { return null.x; } a()`,
+	`function rec() { return rec(); } rec()`,
+	`f(...1)`,
+	`function f() { return 1; } f(...[1], ...5)`,
+
+	// objects
+	`var o = {a: 1, ...{a: 2, b: 3}, c: 4}; o`,
+	`({...[1, 2]})`,
+	`var a = [1, 2]; [0, ...a, 3, ..."hi"]`,
+	`var o = {a: {b: null}}; [o?.a?.b, o.x?.y, o.a?.b?.c.d]`,
+	`var o = null; o?.f()`,
+	`var o = {f: null}; o.f?.()`,
+	`var o = {f: function() { return this.v; }, v: 7}; o?.f()`,
+	`var a = null; a?.[0]`,
+	`var o = {x: 1}; o.y.z`,
+	`var a = [1]; a["x"]`,
+
+	// new
+	`function Point(x, y) { this.x = x; this.y = y; } var p = new Point(1, 2); [p.x, p.y]`,
+	`function F() { this.a = 1; return {b: 2}; } new F()`,
+	`function F() { this.a = 1; return 5; } new F()`,
+	`var e = new Error("boom"); [e.name, e.message]`,
+	`new 5`,
+	`var f = () => 1; new f()`,
+	`function F() { null.x; } new F()`,
+
+	// exceptions
+	`try { throw "x"; } catch (e) { e }`,
+	`try { null.x; } catch (e) { [e.name, e.message] }`,
+	`try { missing; } catch (e) { e.name }`,
+	`try { 1 } finally { 2 }`,
+	`var log = []; try { log[0] = "try"; } finally { log[1] = "finally"; } log`,
+	`var log = []; try { try { throw new TypeError("t"); } finally { log[0] = "inner"; } } catch (e) { log[1] = e.name; } log`,
+	`function f() { try { return "try"; } finally { log = "finally"; } } var log = ""; [f(), log]`,
+	`function f() { try { return "try"; } finally { return "finally"; } } f()`,
+	`function f() { try { throw 1; } catch (e) { return e + 1; } finally { n++; } } var n = 0; [f(), n]`,
+	`var n = 0; for (var i = 0; i < 5; i++) { try { if (i == 3) { break; } continue; } finally { n++; } } [i, n]`,
+	`var s = 0; for (var v of [1, 2, 3]) { try { if (v == 2) { throw v; } s += v; } catch (e) { s += 10 * e; } } s`,
+	`try { throw 1; } catch { "caught" }`,
+	`try { throw {code: 7}; } catch (e) { e.code }`,
+	`throw "uncaught";`,
+	`throw new RangeError("out of range");`,
+	`function f() { throw new Error("deep"); } function g() { f(); } g();`,
+	`try { throw 1; } catch (e) { throw e + 1; }`,
+	`try { throw 1; } finally { 2 }`,
+	`var e = null; try { null.x; } catch (err) { e = err; } e.stack`,
+	`function f() { null.x; } try { f(); } catch (e) { e.stack }`,
+	`var x = 1; try { let x = 2; throw x; } catch (e) { x + e }`,
+	`function f() {} typeof f()`,
+	"var a = [1, [2]]; a[2] = a; `${a} ${({})}`",
+	`function f() { return; } [typeof f(), f()]`,
+	"function f() { return\n5; } typeof f()",
+	`var a = [1]; a[3] = 4; [typeof a[1], a.length]`,
+	`var a = []; try { a[400000000] = 1; } catch (e) { e.name }`,
+	`fn f(a = b, b = 1) { return a; } f()`,
+	`fn f(a = b, b = 1) { return a; } f(2)`,
+	`var b = 3; fn f(a = b, b = 1) { return a; } f()`,
+	`fn f(a, b = a + 1, c) { return [a, b, c]; } [f(1), f(1, 5, 6), f(1, undefined, 7)]`,
+	`fn f(a = 1, ...rest) { return [a, rest, arguments.length]; } f(undefined, 2, 3)`,
+	`function f(n) { if (false) { return 1; } return n; } f(5)`,
+	`function f() { if (false) { return 1; } } typeof f()`,
+	`[1 + undefined, 1 + null, "n=" + 1, "6" / "2", [5] * 2, [1, [2]] + "", ({}) + 1]`,
+	`var r = []; try { undeclared = 1; } catch (e) { r[0] = e.name; } const c = 1; try { c = 2; } catch (e) { r[1] = e.name; } r`,
+	`var o = {}; [typeof o.x, typeof o["y"], typeof [1][3], typeof [1][0.5]]`,
+	`function f() { var x = 1; } [typeof f(), typeof (() => {})()]`,
+	`function f(n) { if (n == 0) { throw "bottom"; } try { return f(n - 1); } finally { } } try { f(3); } catch (e) { e }`,
+}
+
+func TestDifferential(t *testing.T) {
+	for _, input := range differentialTests {
+		p := parser.NewString(input)
+		program := p.Parse()
+		if err := p.Errors().Err(); err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+		expected, expectedErr := eval.Run(program, object.NewEnvironment())
+
+		compiled, err := compiler.Compile(parser.NewString(input).Parse())
+		if err != nil {
+			t.Errorf("%s: compile error %v, interpreter returned %s (error %v)", input, err, eval.Inspect(expected), expectedErr)
+			continue
+		}
+		actual, actualErr := Run(compiled, object.NewEnvironment())
+
+		switch {
+		case expectedErr != nil || actualErr != nil:
+			if expectedErr == nil || actualErr == nil || expectedErr.Error() != actualErr.Error() {
+				t.Errorf("%s: expected error %v. got %v (result %s)", input, expectedErr, actualErr, eval.Inspect(actual))
+				continue
+			}
+			expectedTrace, _ := expectedErr.(*object.Error)
+			actualTrace, _ := actualErr.(*object.Error)
+			if expectedTrace != nil && actualTrace != nil && expectedTrace.Trace() != actualTrace.Trace() {
+				t.Errorf("%s: expected trace\n%s\ngot\n%s", input, expectedTrace.Trace(), actualTrace.Trace())
+			}
+		case canonical(expected) != canonical(actual):
+			t.Errorf("%s: expected %s. got %s", input, canonical(expected), canonical(actual))
+		}
+	}
+}
+
+// canonical formats obj like Inspect, but with the properties of objects
+// sorted so results can be compared.
+func canonical(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.Hash:
+		var pairs []string
+		for _, pair := range obj.Pairs {
+			pairs = append(pairs, pair.Key.Inspect()+": "+canonical(pair.Value))
+		}
+		sort.Strings(pairs)
+		return "{" + strings.Join(pairs, ", ") + "}"
+	case *object.Array:
+		var elements []string
+		for _, e := range obj.Elements {
+			elements = append(elements, canonical(e))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	}
+	return eval.Inspect(obj)
+}
+
+func TestRunGlobals(t *testing.T) {
+	env := object.NewEnvironment()
+	for _, input := range []string{`var total = 1;`, `function bump() { total += 1; return total; }`, `bump()`} {
+		compiled, err := compiler.Compile(parser.NewString(input).Parse())
+		if err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+		if _, err := Run(compiled, env); err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+	}
+	if total, ok := env.Get("total"); !ok || total.Inspect() != "2" {
+		t.Errorf("expected total to be 2. got %v", total)
+	}
+}
+
+func TestRunBudget(t *testing.T) {
+	compiled, err := compiler.Compile(parser.NewString(`while (true) {}`).Parse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := object.NewEnvironment()
+	budget := object.NewBudget()
+	budget.MaxSteps = 1000
+	env.SetBudget(budget)
+
+	_, err = Run(compiled, env)
+	if rerr, ok := err.(*object.Error); !ok || !rerr.Uncatchable {
+		t.Errorf("expected the step limit to stop the loop. got %v", err)
+	}
+}
+
+// topLevel parses input and returns the body of its first statement, a
+// function declaration or a while loop, as a program. It builds programs
+// the parser rejects, such as one with a return outside of a function.
+func topLevel(input string) *ast.Program {
+	var body *ast.BlockStatement
+	switch stmt := parser.NewString(input).Parse().Statements[0].(type) {
+	case *ast.FunctionDeclaration:
+		body = stmt.Function.Body
+	case *ast.WhileStatement:
+		body = stmt.Body
+	}
+	return &ast.Program{Statements: body.Statements}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{`function f() { return 1; }`, "1:16: illegal return statement outside of function"},
+		{`while (true) { if (true) { break; } }`, "1:28: illegal break statement"},
+		{`while (true) { continue; }`, "1:16: illegal continue statement"},
+	}
+	for _, tt := range tests {
+		_, err := compiler.Compile(topLevel(tt.Input))
+		if err == nil || err.Error() != tt.Expected {
+			t.Errorf("%s: expected %q. got %v", tt.Input, tt.Expected, err)
+		}
+	}
+}
+
+func TestCompileLimits(t *testing.T) {
+	elements := strings.Repeat("1, ", 70000) + "1"
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"var a = [" + elements + "]; a.length", "too many array elements"},
+		{"var h = {" + strings.Repeat("a: 1, ", 70000) + "};", "too many object properties"},
+		{"`" + strings.Repeat("${1}", 33000) + "`", "too many template parts"},
+		{"var a = 0; var x = 0; fn f() {} " + strings.Repeat("a++;", 14000) + " for (;;) { x++; f(x); }", "function too large"},
+	}
+	for _, tt := range tests {
+		p := parser.NewString(tt.Input)
+		program := p.Parse()
+		if err := p.Errors().Err(); err != nil {
+			t.Fatalf("%.40s: %v", tt.Input, err)
+		}
+		_, err := compiler.Compile(program)
+		if err == nil || !strings.Contains(err.Error(), tt.Expected) {
+			t.Errorf("%.40s: expected %q. got %v", tt.Input, tt.Expected, err)
+		}
+	}
+
+	// Number constants are added once, so a long sum of the same numbers
+	// compiles.
+	compiled, err := compiler.Compile(parser.NewString(strings.Repeat("1 + ", 70000) + "0").Parse())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result, err := Run(compiled, object.NewEnvironment()); err != nil || result.Inspect() != "70000" {
+		t.Errorf("expected 70000. got %v, %v", result, err)
+	}
+}
+
+const fibSource = `
+function fib(n) {
+	if (n < 2) {
+		return n;
+	}
+	return fib(n - 1) + fib(n - 2);
+}
+fib(20);
+`
+
+const loopSource = `
+var sum = 0;
+for (let i = 0; i < 10000; i++) {
+	if (i % 3 == 0) {
+		sum += i;
+	}
+}
+sum;
+`
+
+func benchmarkEval(b *testing.B, source string) {
+	program := parser.NewString(source).Parse()
+	for i := 0; i < b.N; i++ {
+		if _, err := eval.Run(program, object.NewEnvironment()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkVM(b *testing.B, source string) {
+	compiled, err := compiler.Compile(parser.NewString(source).Parse())
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		if _, err := Run(compiled, object.NewEnvironment()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEvalFib(b *testing.B)  { benchmarkEval(b, fibSource) }
+func BenchmarkVMFib(b *testing.B)    { benchmarkVM(b, fibSource) }
+func BenchmarkEvalLoop(b *testing.B) { benchmarkEval(b, loopSource) }
+func BenchmarkVMLoop(b *testing.B)   { benchmarkVM(b, loopSource) }